@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Claims represents the JWT claims
+type Claims struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	jwt.StandardClaims
+}
+
+// key signs and verifies tokens. It is set by SetKey once the environment
+// is loaded; an empty key would let anyone forge tokens.
+var key []byte
+
+// SetKey sets the token signing key, typically JWT_KEY.
+func SetKey(k string) error {
+	if k == "" {
+		return errors.New("JWT_KEY is not set")
+	}
+	key = []byte(k)
+	return nil
+}
+
+// NewToken signs a token for a user that expires at expires.
+func NewToken(userID, username string, expires time.Time) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("JWT key is not set")
+	}
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// Middleware rejects requests without a valid bearer token and makes the
+// token's user available to CurrentUserID.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get("Authorization")
+		if tokenString == "" {
+			http.Error(w, "Missing token", http.StatusUnauthorized)
+			return
+		}
+
+		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if len(key) == 0 {
+				return nil, errors.New("JWT key is not set")
+			}
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
+			return key, nil
+		})
+
+		if err != nil || !token.Valid {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type claimsKey struct{}
+
+// CurrentUserID returns the user authenticated by Middleware, or an empty
+// string when the request did not go through the middleware.
+func CurrentUserID(r *http.Request) string {
+	claims, ok := r.Context().Value(claimsKey{}).(*Claims)
+	if !ok {
+		return ""
+	}
+	return claims.UserID
+}
+
+// WithUserID returns a copy of r authenticated as userID, as Middleware
+// would. It is meant for tests of handlers behind the middleware.
+func WithUserID(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), claimsKey{}, &Claims{UserID: userID}))
+}
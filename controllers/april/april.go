@@ -3,9 +3,13 @@ package april
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"nutrishe/locale"
 	"nutrishe/models"
+	"nutrishe/nutrition"
+	"strings"
 	"time"
 )
 
@@ -19,7 +23,42 @@ type Food struct {
 	Protein       *float64 `json:"protein"`
 	Fiber         *float64 `json:"fiber"`
 	Calcium       *int     `json:"calcium"`
-	Type          string   `json:"type"`
+	Sugar         *float64 `json:"sugar"`
+	SaturatedFat  *float64 `json:"saturated_fat"`
+	Sodium        *int     `json:"sodium"`
+	Iron          *float64 `json:"iron"`
+	// PricePerServing is in rupiah, nil when unknown.
+	PricePerServing *int     `json:"price_per_serving"`
+	Type            string   `json:"type"`
+	Tags            []string `json:"tags"`
+	// Quantity is the number of servings logged, only set for logged meals.
+	Quantity float64 `json:"quantity,omitempty"`
+
+	Score nutrition.FoodScore `json:"score"`
+}
+
+// facts converts the food into the input used for nutrition scoring.
+func (f Food) facts() nutrition.Facts {
+	return nutrition.Facts{
+		ServingGrams: f.Serving,
+		Calories:     f.Calories,
+		Protein:      f.Protein,
+		Fiber:        f.Fiber,
+		Sugar:        f.Sugar,
+		SaturatedFat: f.SaturatedFat,
+		Sodium:       f.Sodium,
+		Calcium:      f.Calcium,
+		Iron:         f.Iron,
+	}
+}
+
+// dayQuality scores the foods logged in a day, counting every serving.
+func dayQuality(meals []Food) nutrition.DayScore {
+	facts := make([]nutrition.Facts, 0, len(meals))
+	for _, food := range meals {
+		facts = append(facts, food.facts().Scaled(food.Quantity))
+	}
+	return nutrition.ScoreDay(facts)
 }
 
 type DailyMeal struct {
@@ -38,124 +77,124 @@ type DailyMealRequest struct {
 	UserID   string `json:"user_id"`
 	MealDate string `json:"meal_date"`
 	FoodID   string `json:"food_id"`
+	// FoodIDs logs several foods at once, e.g. a whole day of a meal plan.
+	FoodIDs []string `json:"food_ids"`
+	// Foods logs foods with their servings, e.g. the portions confirmed
+	// from /meal_photo or /meal_text.
+	Foods []models.LoggedFood `json:"foods"`
+	// Force logs the food even when it contains one of the user's allergens.
+	Force bool `json:"force"`
+}
+
+// maxServings is the most servings of one food logged at once.
+const maxServings = 20
+
+// foods returns the requested foods. FoodID and FoodIDs are one serving
+// each, and a food listed more than once is logged as that many servings.
+// It returns false when a serving count is out of range.
+func (req DailyMealRequest) foods() ([]models.LoggedFood, bool) {
+	var foods []models.LoggedFood
+	for _, id := range append([]string{req.FoodID}, req.FoodIDs...) {
+		if id != "" {
+			foods = append(foods, models.LoggedFood{FoodID: id, Servings: 1})
+		}
+	}
+	for _, food := range req.Foods {
+		if food.FoodID == "" {
+			continue
+		}
+		if food.Servings <= 0 || food.Servings > maxServings {
+			return nil, false
+		}
+		foods = append(foods, food)
+	}
+	return foods, true
 }
 
 func LogMeal(w http.ResponseWriter, r *http.Request) {
+	lang := locale.FromRequest(r)
 	var mealReq DailyMealRequest
 	err := json.NewDecoder(r.Body).Decode(&mealReq)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_payload"), http.StatusBadRequest)
 		return
 	}
 
 	// Validate and parse the date
 	mealDate, err := time.Parse("2006-01-02", mealReq.MealDate)
 	if err != nil {
-		http.Error(w, "Invalid date format, please use YYYY-MM-DD", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_date"), http.StatusBadRequest)
 		return
 	}
 
 	db := models.GetDB()
 	if db == nil {
-		http.Error(w, "Database connection is not initialized", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "db_not_initialized"), http.StatusInternalServerError)
 		return
 	}
 
-	// Start transaction to ensure atomicity
-	tx, err := db.Begin()
-	if err != nil {
-		log.Printf("Failed to start transaction: %v", err)
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+	foods, ok := mealReq.foods()
+	if !ok {
+		http.Error(w, locale.Message(lang, "invalid_servings"), http.StatusBadRequest)
 		return
 	}
-	defer func() {
-		if err != nil {
-			log.Printf("Transaction failed, rolling back: %v", err)
-			tx.Rollback()
-			http.Error(w, "Transaction failed", http.StatusInternalServerError)
-		}
-	}()
-
-	// Check if daily meal already exists for the user and meal date
-	var trackID string
-	var totalCalories int
-	query := "SELECT TrackID, TotalCalories FROM daily_meal WHERE UserID = ? AND MealDate = ?"
-	err = tx.QueryRow(query, mealReq.UserID, mealDate).Scan(&trackID, &totalCalories)
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Failed to check existing daily meal: %v", err)
-		http.Error(w, "Failed to check existing daily meal", http.StatusInternalServerError)
+	if len(foods) == 0 {
+		http.Error(w, locale.Message(lang, "missing_food_id"), http.StatusBadRequest)
 		return
 	}
-
-	if trackID == "" {
-		// Generate new TrackID
-		trackID, err = models.GenerateSequentialTrackID()
-		if err != nil {
-			log.Printf("Failed to generate TrackID: %v", err)
-			http.Error(w, "Failed to generate TrackID", http.StatusInternalServerError)
-			return
-		}
-
-		// Insert new daily meal
-		_, err = tx.Exec("INSERT INTO daily_meal (TrackID, UserID, MealDate, TotalCalories) VALUES (?, ?, ?, 0)", trackID, mealReq.UserID, mealDate)
-		if err != nil {
-			log.Printf("Failed to create daily meal: %v", err)
-			http.Error(w, "Failed to create daily meal", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// Check if food_id exists in the food table
-	var foodExists bool
-	err = tx.QueryRow("SELECT COUNT(*) > 0 FROM food WHERE FoodID = ?", mealReq.FoodID).Scan(&foodExists)
-	if err != nil || !foodExists {
-		log.Printf("Invalid food_id: %v", mealReq.FoodID)
-		http.Error(w, "Invalid food_id", http.StatusBadRequest)
-		return
+	foodIDs := make([]string, len(foods))
+	for i, food := range foods {
+		foodIDs[i] = food.FoodID
 	}
 
-	// Insert meal detail
-	_, err = tx.Exec("INSERT INTO meal_detail (TrackID, FoodID) VALUES (?, ?)", trackID, mealReq.FoodID)
+	// Compare the food's tags with the user's allergies and diets
+	conflicts, err := models.CheckFoodConflicts(db, mealReq.UserID, foodIDs)
 	if err != nil {
-		log.Printf("Failed to add meal detail: %v", err)
-		http.Error(w, "Failed to add meal detail", http.StatusInternalServerError)
+		log.Printf("Failed to check dietary conflicts: %v", err)
+		http.Error(w, locale.Message(lang, "conflict_check_failed"), http.StatusInternalServerError)
 		return
 	}
-
-	// Calculate updated total calories
-	var newTotalCalories int
-	err = tx.QueryRow("SELECT SUM(f.Calories) FROM meal_detail md JOIN food f ON md.FoodID = f.FoodID WHERE md.TrackID = ?", trackID).Scan(&newTotalCalories)
-	if err != nil {
-		log.Printf("Failed to calculate total calories: %v", err)
-		http.Error(w, "Failed to calculate total calories", http.StatusInternalServerError)
+	if models.HasAllergyConflict(conflicts) && !mealReq.Force {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":   locale.Message(lang, "allergy_conflict"),
+			"conflicts": conflicts,
+		})
 		return
 	}
 
-	// Update total calories in daily_meal table
-	_, err = tx.Exec("UPDATE daily_meal SET TotalCalories = ? WHERE TrackID = ?", newTotalCalories, trackID)
-	if err != nil {
-		log.Printf("Failed to update total calories: %v", err)
-		http.Error(w, "Failed to update total calories", http.StatusInternalServerError)
+	err = models.LogMealFoods(db, mealReq.UserID, mealDate, foods)
+	if errors.Is(err, models.ErrInvalidFood) {
+		// The food ID after the colon tells the client which one is wrong
+		http.Error(w, locale.Message(lang, "invalid_food")+strings.TrimPrefix(err.Error(), models.ErrInvalidFood.Error()), http.StatusBadRequest)
 		return
 	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		log.Printf("Failed to commit transaction: %v", err)
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+	if err != nil {
+		log.Printf("Failed to log meal: %v", err)
+		http.Error(w, locale.Message(lang, "log_meal_failed"), http.StatusInternalServerError)
 		return
 	}
 
-	// Respond with success
+	// Respond with success, including any dietary warnings
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Meal logged successfully"})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": locale.Message(lang, "meal_logged"), "warnings": conflicts})
 }
 
 func GetFoodList(w http.ResponseWriter, r *http.Request) {
+	lang := locale.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+
 	db := models.GetDB()
-	rows, err := db.Query("SELECT FoodID, Name, Serving, Calories, Fat, Carbohydrates, Protein, Fiber, Calcium, Type FROM food")
+	tags, err := models.GetAllFoodTags(db)
 	if err != nil {
-		http.Error(w, "Failed to retrieve food list: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "food_tags_failed")+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query("SELECT f.FoodID, COALESCE(ft.Name, f.Name), f.Serving, f.Calories, f.Fat, f.Carbohydrates, f.Protein, f.Fiber, f.Calcium, fn.Sugar, fn.SaturatedFat, fn.Sodium, fn.Iron, fp.PricePerServing, f.Type FROM food f LEFT JOIN food_translation ft ON ft.FoodID = f.FoodID AND ft.Lang = ? LEFT JOIN food_nutrient fn ON fn.FoodID = f.FoodID LEFT JOIN food_price fp ON fp.FoodID = f.FoodID", lang)
+	if err != nil {
+		http.Error(w, locale.Message(lang, "food_list_failed")+": "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -167,8 +206,8 @@ func GetFoodList(w http.ResponseWriter, r *http.Request) {
 		var fat, carbohydrates, protein, fiber sql.NullFloat64
 		var calcium sql.NullInt64
 
-		if err := rows.Scan(&food.FoodID, &food.Name, &food.Serving, &food.Calories, &fat, &carbohydrates, &protein, &fiber, &calcium, &food.Type); err != nil {
-			http.Error(w, "Failed to scan food item: "+err.Error(), http.StatusInternalServerError)
+		if err := rows.Scan(&food.FoodID, &food.Name, &food.Serving, &food.Calories, &fat, &carbohydrates, &protein, &fiber, &calcium, &food.Sugar, &food.SaturatedFat, &food.Sodium, &food.Iron, &food.PricePerServing, &food.Type); err != nil {
+			http.Error(w, locale.Message(lang, "food_scan_failed")+": "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
@@ -203,19 +242,26 @@ func GetFoodList(w http.ResponseWriter, r *http.Request) {
 			food.Calcium = nil
 		}
 
+		food.Tags = tags[food.FoodID]
+		if food.Tags == nil {
+			food.Tags = []string{}
+		}
+
+		food.Score = nutrition.ScoreFood(food.facts())
+
 		foodsByType[food.Type] = append(foodsByType[food.Type], food)
 	}
 
 	// Handle any errors encountered during iteration
 	if err := rows.Err(); err != nil {
-		http.Error(w, "Failed to iterate through food list: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "food_iterate_failed")+": "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Marshal foodsByType to JSON and write response
 	jsonBytes, err := json.Marshal(foodsByType)
 	if err != nil {
-		http.Error(w, "Failed to marshal foodsByType to JSON: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "marshal_failed")+": "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -224,6 +270,9 @@ func GetFoodList(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
+	lang := locale.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+
 	var requestBody struct {
 		UserID   string `json:"user_id"`
 		MealDate string `json:"meal_date"`
@@ -232,7 +281,7 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 	// Decode the JSON request body
 	err := json.NewDecoder(r.Body).Decode(&requestBody)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_payload"), http.StatusBadRequest)
 		return
 	}
 
@@ -240,20 +289,20 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 	mealDateStr := requestBody.MealDate
 
 	if userID == "" || mealDateStr == "" {
-		http.Error(w, "Missing user_id or meal_date", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "missing_user_or_date"), http.StatusBadRequest)
 		return
 	}
 
 	// Validate and parse the date
 	mealDate, err := time.Parse("2006-01-02", mealDateStr)
 	if err != nil {
-		http.Error(w, "Invalid date format, please use YYYY-MM-DD", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_date"), http.StatusBadRequest)
 		return
 	}
 
 	db := models.GetDB()
 	if db == nil {
-		http.Error(w, "Database connection is not initialized", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "db_not_initialized"), http.StatusInternalServerError)
 		return
 	}
 
@@ -262,14 +311,14 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 	err = db.QueryRow("SELECT TrackID, TotalCalories FROM daily_meal WHERE UserID = ? AND MealDate = ?", userID, mealDate).Scan(&trackID, &totalCalories)
 	if err != nil {
 		log.Printf("Failed to retrieve track ID: %v", err)
-		http.Error(w, "Failed to retrieve track ID", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "track_id_failed"), http.StatusInternalServerError)
 		return
 	}
 
-	rows, err := db.Query("SELECT f.FoodID, f.Name, f.Serving, f.Calories, f.Fat, f.Carbohydrates, f.Protein, f.Fiber, f.Calcium, f.Type FROM meal_detail md JOIN food f ON md.FoodID = f.FoodID WHERE md.TrackID = ?", trackID)
+	rows, err := db.Query("SELECT f.FoodID, COALESCE(ft.Name, f.Name), f.Serving, f.Calories, f.Fat, f.Carbohydrates, f.Protein, f.Fiber, f.Calcium, fn.Sugar, fn.SaturatedFat, fn.Sodium, fn.Iron, fp.PricePerServing, f.Type, COALESCE(q.Quantity, 1) FROM meal_detail md JOIN food f ON md.FoodID = f.FoodID LEFT JOIN meal_detail_quantity q ON q.TrackID = md.TrackID AND q.FoodID = md.FoodID LEFT JOIN food_translation ft ON ft.FoodID = f.FoodID AND ft.Lang = ? LEFT JOIN food_nutrient fn ON fn.FoodID = f.FoodID LEFT JOIN food_price fp ON fp.FoodID = f.FoodID WHERE md.TrackID = ?", lang, trackID)
 	if err != nil {
 		log.Printf("Failed to retrieve meals: %v", err)
-		http.Error(w, "Failed to retrieve meals", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "meals_failed"), http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
@@ -280,9 +329,9 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 		var calcium sql.NullInt64
 		var fiber sql.NullFloat64
 
-		if err := rows.Scan(&food.FoodID, &food.Name, &food.Serving, &food.Calories, &food.Fat, &food.Carbohydrates, &food.Protein, &fiber, &calcium, &food.Type); err != nil {
+		if err := rows.Scan(&food.FoodID, &food.Name, &food.Serving, &food.Calories, &food.Fat, &food.Carbohydrates, &food.Protein, &fiber, &calcium, &food.Sugar, &food.SaturatedFat, &food.Sodium, &food.Iron, &food.PricePerServing, &food.Type, &food.Quantity); err != nil {
 			log.Printf("Failed to scan meal item: %v", err)
-			http.Error(w, "Failed to scan meal item", http.StatusInternalServerError)
+			http.Error(w, locale.Message(lang, "food_scan_failed"), http.StatusInternalServerError)
 			return
 		}
 
@@ -299,12 +348,20 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 			food.Calcium = nil
 		}
 
+		food.Tags, err = models.GetFoodTags(db, food.FoodID)
+		if err != nil {
+			log.Printf("Failed to retrieve food tags: %v", err)
+			http.Error(w, locale.Message(lang, "food_tags_failed"), http.StatusInternalServerError)
+			return
+		}
+
+		food.Score = nutrition.ScoreFood(food.facts())
 		meals = append(meals, food)
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Printf("Error iterating over rows: %v", err)
-		http.Error(w, "Error iterating over rows", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "food_iterate_failed"), http.StatusInternalServerError)
 		return
 	}
 
@@ -314,11 +371,13 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := struct {
-		TotalCalories int    `json:"total_calories"`
-		Meals         []Food `json:"meals"`
+		TotalCalories int                `json:"total_calories"`
+		Meals         []Food             `json:"meals"`
+		Quality       nutrition.DayScore `json:"quality"`
 	}{
 		TotalCalories: totalCalories,
 		Meals:         meals,
+		Quality:       dayQuality(meals),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -327,23 +386,24 @@ func GetMealsByDate(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteMealDetail(w http.ResponseWriter, r *http.Request) {
+	lang := locale.FromRequest(r)
 	var mealReq DailyMealRequest
 	err := json.NewDecoder(r.Body).Decode(&mealReq)
 	if err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_payload"), http.StatusBadRequest)
 		return
 	}
 
 	// Validate and parse the date
 	mealDate, err := time.Parse("2006-01-02", mealReq.MealDate)
 	if err != nil {
-		http.Error(w, "Invalid date format, please use YYYY-MM-DD", http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_date"), http.StatusBadRequest)
 		return
 	}
 
 	db := models.GetDB()
 	if db == nil {
-		http.Error(w, "Database connection is not initialized", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "db_not_initialized"), http.StatusInternalServerError)
 		return
 	}
 
@@ -351,14 +411,14 @@ func DeleteMealDetail(w http.ResponseWriter, r *http.Request) {
 	tx, err := db.Begin()
 	if err != nil {
 		log.Printf("Failed to start transaction: %v", err)
-		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "delete_meal_failed"), http.StatusInternalServerError)
 		return
 	}
 	defer func() {
 		if err != nil {
 			log.Printf("Transaction failed, rolling back: %v", err)
 			tx.Rollback()
-			http.Error(w, "Transaction failed", http.StatusInternalServerError)
+			http.Error(w, locale.Message(lang, "delete_meal_failed"), http.StatusInternalServerError)
 		}
 	}()
 
@@ -368,10 +428,10 @@ func DeleteMealDetail(w http.ResponseWriter, r *http.Request) {
 	err = tx.QueryRow(query, mealReq.UserID, mealDate).Scan(&trackID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "No meal found for the given date", http.StatusNotFound)
+			http.Error(w, locale.Message(lang, "meal_not_found"), http.StatusNotFound)
 		} else {
 			log.Printf("Failed to get TrackID: %v", err)
-			http.Error(w, "Failed to get TrackID", http.StatusInternalServerError)
+			http.Error(w, locale.Message(lang, "track_id_failed"), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -383,26 +443,32 @@ func DeleteMealDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, err = tx.Exec("DELETE FROM meal_detail_quantity WHERE TrackID = ? AND FoodID = ?", trackID, mealReq.FoodID)
+	if err != nil {
+		log.Printf("Failed to delete meal quantity: %v", err)
+		return
+	}
+
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		log.Printf("Failed to get rows affected: %v", err)
-		http.Error(w, "Failed to delete meal detail", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "delete_meal_failed"), http.StatusInternalServerError)
 		return
 	}
 	if rowsAffected == 0 {
-		http.Error(w, "No matching meal detail found", http.StatusNotFound)
+		http.Error(w, locale.Message(lang, "meal_detail_not_found"), http.StatusNotFound)
 		return
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.Printf("Failed to commit transaction: %v", err)
-		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		http.Error(w, locale.Message(lang, "delete_meal_failed"), http.StatusInternalServerError)
 		return
 	}
 
 	// Respond with success
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Meal detail deleted successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": locale.Message(lang, "meal_deleted")})
 }
@@ -0,0 +1,30 @@
+package april
+
+import "testing"
+
+func f64(v float64) *float64 { return &v }
+
+func TestDayQuality(t *testing.T) {
+	// Half the fiber and protein targets in one serving
+	tempe := Food{FoodID: "F001", Serving: 100, Calories: 200, Protein: f64(25), Fiber: f64(12.5), Sugar: f64(1), SaturatedFat: f64(1)}
+
+	tests := []struct {
+		name     string
+		quantity float64
+		want     map[string]int
+	}{
+		{"one serving", 1, map[string]int{"fiber": 10, "protein": 10}},
+		{"two servings", 2, map[string]int{"fiber": 20, "protein": 20}},
+		{"half a serving", 0.5, map[string]int{"fiber": 5, "protein": 5}},
+	}
+	for _, tt := range tests {
+		food := tempe
+		food.Quantity = tt.quantity
+		got := dayQuality([]Food{food})
+		for component, want := range tt.want {
+			if got.Components[component] != want {
+				t.Errorf("%s: %s = %d, want %d", tt.name, component, got.Components[component], want)
+			}
+		}
+	}
+}
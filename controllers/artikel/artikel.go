@@ -2,15 +2,21 @@ package artikel
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
+	"nutrishe/locale"
+	"nutrishe/models"
+	"nutrishe/search"
+	"strconv"
+	"strings"
 )
 
+// SearchPrompt is a search request. StartIndex is the one-based index of
+// the first web result, for paging; in-house articles are only returned on
+// the first page.
 type SearchPrompt struct {
-	Query string `json:"Query"`
+	Query      string `json:"Query"`
+	StartIndex int    `json:"StartIndex"`
 }
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -21,83 +27,99 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-type SearchResult struct {
-	Kind string `json:"kind"`
-	Url  struct {
-		Type     string `json:"type"`
-		Template string `json:"template"`
-	} `json:"url"`
-	Queries struct {
-		Request []struct {
-			Title        string `json:"title"`
-			TotalResults string `json:"totalResults"`
-			SearchTerms  string `json:"searchTerms"`
-			Count        int    `json:"count"`
-			StartIndex   int    `json:"startIndex"`
-		} `json:"request"`
-	} `json:"queries"`
-	Items []struct {
-		Title   string `json:"title"`
-		Link    string `json:"link"`
-		Snippet string `json:"snippet"`
-	} `json:"items"`
+// maxResults is how many articles a search returns. Web results can be
+// paged up to maxStartIndex, since Google Custom Search rejects a start
+// index plus page size above 100.
+const (
+	maxResults    = 10
+	maxStartIndex = 90
+)
+
+// Where a search result comes from.
+const (
+	SourceInHouse = "in_house"
+	SourceWeb     = "web"
+)
+
+// SearchItem is a search result. In-house articles carry their ArticleID
+// and link to /article.
+type SearchItem struct {
+	search.Article
+	Source    string `json:"source"`
+	ArticleID int    `json:"article_id,omitempty"`
 }
 
+// SearchArticles returns in-house articles first, followed by web results.
+// When the provider has more web results, the X-Next-Start-Index header
+// holds the StartIndex of the next page. Pages may hold fewer than
+// maxResults web results, since untrusted ones are dropped.
 func SearchArticles(w http.ResponseWriter, r *http.Request) {
 	log.Println("search artikel")
 
+	lang := locale.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+
 	var data SearchPrompt
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, locale.Message(lang, "invalid_payload")+": "+err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	searchQuery := data.Query //search query nya
-	maxResults := 10          //maxnya mau brp artikel
-	searchResults, err := search(searchQuery, maxResults)
-	if err != nil {
-		log.Fatalf("Error performing search: %v", err)
+	data.Query = strings.TrimSpace(data.Query)
+	if data.Query == "" {
+		http.Error(w, locale.Message(lang, "invalid_payload")+": Query is required", http.StatusBadRequest)
+		return
 	}
-
-	log.Println("Search Results:")
-	for _, item := range searchResults.Items {
-		log.Printf("Title: %s\n", item.Title)
-		log.Printf("Link: %s\n", item.Link)
-		log.Printf("Snippet: %s\n\n", item.Snippet)
+	if data.StartIndex == 0 {
+		data.StartIndex = 1
 	}
-
-	respondJSON(w, http.StatusOK, searchResults.Items)
-}
-
-func search(query string, maxResults int) (*SearchResult, error) {
-	baseURL := "https://www.googleapis.com/customsearch/v1"
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return nil, err
+	if data.StartIndex < 1 || data.StartIndex > maxStartIndex {
+		http.Error(w, locale.Message(lang, "invalid_payload")+": StartIndex must be from 1 to "+strconv.Itoa(maxStartIndex), http.StatusBadRequest)
+		return
 	}
 
-	q := u.Query()
-	q.Set("key", os.Getenv("Custom_Search_API_KEY"))
-	q.Set("cx", os.Getenv("cx"))
-	q.Set("q", query)
-	q.Set("num", fmt.Sprintf("%d", maxResults))
-	u.RawQuery = q.Encode()
+	items := []SearchItem{}
+	if data.StartIndex == 1 {
+		inHouse, err := models.SearchPublishedArticles(models.GetDB(), data.Query, lang, maxResults)
+		if err != nil {
+			// The web results are still useful on their own
+			log.Printf("Error searching in-house articles: %v", err)
+		}
+		for _, a := range inHouse {
+			items = append(items, inHouseItem(a))
+		}
+	}
 
-	resp, err := http.Get(u.String())
+	query := search.Query{Text: data.Query, Lang: lang, Limit: maxResults, Start: data.StartIndex - 1}
+	web, err := search.Get().Search(r.Context(), query)
 	if err != nil {
-		return nil, err
+		log.Printf("Error performing search: %v", err)
+		if len(items) == 0 {
+			respondJSON(w, http.StatusBadGateway, map[string]string{"message": locale.Message(lang, "search_failed")})
+			return
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, err
+	for _, a := range web.Articles {
+		items = append(items, SearchItem{Article: a, Source: SourceWeb})
 	}
-
-	var result SearchResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	if next := data.StartIndex + maxResults; web.More && next <= maxStartIndex {
+		w.Header().Set("X-Next-Start-Index", strconv.Itoa(next))
 	}
 
-	return &result, nil
+	respondJSON(w, http.StatusOK, items)
+}
+
+// inHouseItem links an in-house article to /article. It is written by a
+// nutritionist, so it is always trusted.
+func inHouseItem(a models.Article) SearchItem {
+	return SearchItem{
+		Article: search.Article{
+			Title:       a.Title,
+			Link:        "/article?article_id=" + strconv.Itoa(a.ArticleID),
+			Snippet:     a.Summary,
+			Credibility: search.CredibilityTrusted,
+		},
+		Source:    SourceInHouse,
+		ArticleID: a.ArticleID,
+	}
 }
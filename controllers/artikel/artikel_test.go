@@ -0,0 +1,125 @@
+package artikel
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nutrishe/models"
+	"nutrishe/models/dbtest"
+	"nutrishe/search"
+)
+
+// inHouseArticles answers the in-house article search with one published
+// article when found is set; everything else finds nothing.
+func inHouseArticles(found bool) dbtest.Responder {
+	return func(query string, args []driver.Value) ([][]driver.Value, error) {
+		if found && strings.Contains(query, "FROM article WHERE Status") {
+			return [][]driver.Value{{int64(7), "Zat besi saat haid", "Bayam dan hati ayam", "", "id", "US009", models.ArticlePublished, "2026-01-02 08:00:00", "2026-01-02 08:00:00", "2026-01-02 08:00:00"}}, nil
+		}
+		return nil, nil
+	}
+}
+
+// webArticles returns n web results mentioning bayam.
+func webArticles(n int) []search.Article {
+	articles := []search.Article{}
+	for i := 0; i < n; i++ {
+		articles = append(articles, search.Article{Title: fmt.Sprintf("Bayam %d", i+1), Link: fmt.Sprintf("https://example.com/%d", i+1)})
+	}
+	return articles
+}
+
+// withDenied moves the article at i to a denied domain.
+func withDenied(articles []search.Article, i int) []search.Article {
+	articles[i].Link = "https://hoax.example.com/detox"
+	return articles
+}
+
+func TestSearchArticles(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		inHouse    bool
+		web        search.Searcher
+		wantStatus int
+		wantLinks  []string
+		wantNext   string
+	}{
+		{"in-house first", `{"Query":"bayam"}`, true, &search.Fake{Articles: webArticles(2)}, http.StatusOK,
+			[]string{"/article?article_id=7", "https://example.com/1", "https://example.com/2"}, ""},
+		{"full page", `{"Query":"bayam"}`, false, &search.Fake{Articles: webArticles(25)}, http.StatusOK,
+			[]string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4", "https://example.com/5",
+				"https://example.com/6", "https://example.com/7", "https://example.com/8", "https://example.com/9", "https://example.com/10"}, "11"},
+		{"filtered link on a full page", `{"Query":"bayam"}`, false, search.NewTrust(&search.Fake{Articles: withDenied(webArticles(25), 2)}, nil, []string{"hoax.example.com"}), http.StatusOK,
+			[]string{"https://example.com/1", "https://example.com/2", "https://example.com/4", "https://example.com/5",
+				"https://example.com/6", "https://example.com/7", "https://example.com/8", "https://example.com/9", "https://example.com/10"}, "11"},
+		{"later page skips in-house", `{"Query":"bayam","StartIndex":21}`, true, &search.Fake{Articles: webArticles(25)}, http.StatusOK,
+			[]string{"https://example.com/21", "https://example.com/22", "https://example.com/23", "https://example.com/24", "https://example.com/25"}, ""},
+		{"web fails with in-house results", `{"Query":"bayam"}`, true, &search.Fake{Err: errors.New("quota exceeded")}, http.StatusOK,
+			[]string{"/article?article_id=7"}, ""},
+		{"web fails", `{"Query":"bayam"}`, false, &search.Fake{Err: errors.New("quota exceeded")}, http.StatusBadGateway, nil, ""},
+		{"no query", `{"Query":"  "}`, false, &search.Fake{}, http.StatusBadRequest, nil, ""},
+		{"start past the last page", `{"Query":"bayam","StartIndex":91}`, false, &search.Fake{}, http.StatusBadRequest, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := search.Get()
+			t.Cleanup(func() {
+				search.SetSearcher(prev)
+				models.SetDB(nil)
+			})
+			search.SetSearcher(tt.web)
+			db, _ := dbtest.Open(inHouseArticles(tt.inHouse))
+			models.SetDB(db)
+
+			w := httptest.NewRecorder()
+			SearchArticles(w, httptest.NewRequest(http.MethodPost, "/search_articles", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("X-Next-Start-Index"); got != tt.wantNext {
+				t.Errorf("X-Next-Start-Index = %q, want %q", got, tt.wantNext)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var items []SearchItem
+			if err := json.NewDecoder(w.Body).Decode(&items); err != nil {
+				t.Fatal(err)
+			}
+			links := []string{}
+			for _, item := range items {
+				links = append(links, item.Link)
+			}
+			if strings.Join(links, " ") != strings.Join(tt.wantLinks, " ") {
+				t.Errorf("links = %v, want %v", links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestSearchArticlesHidesErrors(t *testing.T) {
+	prev := search.Get()
+	t.Cleanup(func() { search.SetSearcher(prev) })
+	search.SetSearcher(&search.Fake{Err: errors.New(`Get "https://www.googleapis.com/customsearch/v1?key=secret-key": timeout`)})
+
+	// Later pages do not search the in-house articles
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/search_articles", strings.NewReader(`{"Query":"bayam","StartIndex":11}`))
+	r.Header.Set("Accept-Language", "en")
+	SearchArticles(w, r)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadGateway)
+	}
+	if got, want := strings.TrimSpace(w.Body.String()), `{"message":"Failed to search articles"}`; got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,177 @@
+package artikel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrishe/auth"
+	"nutrishe/models"
+)
+
+// defaultHistoryLimit is how many reads /reading_history returns by default.
+const defaultHistoryLimit = 50
+
+type BookmarkRequest struct {
+	Link    string `json:"link"`
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+	// RemindAt is an optional "read later" time, RFC 3339 or YYYY-MM-DD.
+	RemindAt string `json:"remind_at"`
+}
+
+// validLink accepts web links and links to in-house articles, as returned
+// by /search_articles.
+func validLink(link string) bool {
+	if strings.HasPrefix(link, "/article?article_id=") {
+		return true
+	}
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && len(link) <= 2048
+}
+
+func truncate(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max])
+	}
+	return text
+}
+
+func parseRemindAt(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// BookmarkArticle saves a search result for the authenticated user, or
+// updates its reminder when it is already saved.
+func BookmarkArticle(w http.ResponseWriter, r *http.Request) {
+	var req BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+	req.Link = strings.TrimSpace(req.Link)
+	if !validLink(req.Link) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "link must be an http(s) URL or an in-house article link"})
+		return
+	}
+	remindAt, err := parseRemindAt(req.RemindAt)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid remind_at, use RFC 3339 or YYYY-MM-DD"})
+		return
+	}
+
+	bookmark := &models.Bookmark{
+		Link:     req.Link,
+		Title:    truncate(strings.TrimSpace(req.Title), 255),
+		Snippet:  truncate(strings.TrimSpace(req.Snippet), 1000),
+		RemindAt: remindAt,
+	}
+	if err := models.SaveBookmark(models.GetDB(), auth.CurrentUserID(r), bookmark); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error saving bookmark", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, bookmark)
+}
+
+// RemoveBookmark deletes a bookmark of the authenticated user.
+func RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	var req BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	found, err := models.DeleteBookmark(models.GetDB(), auth.CurrentUserID(r), strings.TrimSpace(req.Link))
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error removing bookmark", "error": err.Error()})
+		return
+	}
+	if !found {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": "Bookmark not found"})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Bookmark removed"})
+}
+
+// ListBookmarks lists the authenticated user's saved articles.
+func ListBookmarks(w http.ResponseWriter, r *http.Request) {
+	bookmarks, err := models.ListBookmarks(models.GetDB(), auth.CurrentUserID(r))
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving bookmarks", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, bookmarks)
+}
+
+// ListReminders lists saved articles whose "read later" time has passed
+// and that the user has not read yet.
+func ListReminders(w http.ResponseWriter, r *http.Request) {
+	reminders, err := models.DueReminders(models.GetDB(), auth.CurrentUserID(r), time.Now())
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving reminders", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, reminders)
+}
+
+// ReadArticle records that the authenticated user opened an article, which
+// also settles its reminder.
+func ReadArticle(w http.ResponseWriter, r *http.Request) {
+	var req BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+	req.Link = strings.TrimSpace(req.Link)
+	if !validLink(req.Link) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "link must be an http(s) URL or an in-house article link"})
+		return
+	}
+
+	read := &models.ArticleRead{Link: req.Link, Title: truncate(strings.TrimSpace(req.Title), 255)}
+	if err := models.RecordArticleRead(models.GetDB(), auth.CurrentUserID(r), read); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error recording read", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, read)
+}
+
+// ReadingHistory lists the authenticated user's most recent reads, up to
+// ?limit.
+func ReadingHistory(w http.ResponseWriter, r *http.Request) {
+	limit := defaultHistoryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			respondJSON(w, http.StatusBadRequest, map[string]string{"message": "limit must be from 1 to 500"})
+			return
+		}
+		limit = n
+	}
+
+	history, err := models.GetReadingHistory(models.GetDB(), auth.CurrentUserID(r), limit)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving reading history", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,210 @@
+package artikel
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nutrishe/auth"
+	"nutrishe/locale"
+	"nutrishe/models"
+)
+
+type PublishArticleRequest struct {
+	ArticleID int  `json:"article_id"`
+	Publish   bool `json:"publish"`
+}
+
+// requireNutritionist writes a 403 and returns false unless the
+// authenticated user is a nutritionist.
+func requireNutritionist(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := auth.CurrentUserID(r)
+	ok, err := models.IsNutritionist(models.GetDB(), userID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error checking role", "error": err.Error()})
+		return "", false
+	}
+	if !ok {
+		respondJSON(w, http.StatusForbidden, map[string]string{"message": "Only nutritionists can manage articles"})
+		return "", false
+	}
+	return userID, true
+}
+
+// getAuthoredArticle writes an error and returns nil unless the article
+// exists and was written by userID.
+func getAuthoredArticle(w http.ResponseWriter, articleID int, userID string) *models.Article {
+	article, err := models.GetArticle(models.GetDB(), articleID)
+	if err == models.ErrArticleNotFound {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": err.Error()})
+		return nil
+	}
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving article", "error": err.Error()})
+		return nil
+	}
+	if article.AuthorID != userID {
+		respondJSON(w, http.StatusForbidden, map[string]string{"message": "Only the author can change this article"})
+		return nil
+	}
+	return article
+}
+
+// decodeArticle reads and validates an article from the request body.
+func decodeArticle(w http.ResponseWriter, r *http.Request) *models.Article {
+	var article models.Article
+	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return nil
+	}
+
+	if err := models.NormalizeArticle(&article); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return nil
+	}
+	if article.Lang == "" {
+		article.Lang = locale.Default
+	}
+	if !locale.IsSupported(article.Lang) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Unsupported language: " + article.Lang})
+		return nil
+	}
+	if article.Title == "" || strings.TrimSpace(article.Body) == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "title and body are required"})
+		return nil
+	}
+	return &article
+}
+
+// CreateArticle stores a new draft article by the authenticated
+// nutritionist.
+func CreateArticle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireNutritionist(w, r)
+	if !ok {
+		return
+	}
+
+	article := decodeArticle(w, r)
+	if article == nil {
+		return
+	}
+	article.AuthorID = userID
+
+	if err := models.CreateArticle(models.GetDB(), article); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error creating article", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, article)
+}
+
+// UpdateArticle changes an article of the authenticated nutritionist.
+func UpdateArticle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireNutritionist(w, r)
+	if !ok {
+		return
+	}
+
+	update := decodeArticle(w, r)
+	if update == nil {
+		return
+	}
+	article := getAuthoredArticle(w, update.ArticleID, userID)
+	if article == nil {
+		return
+	}
+
+	article.Title, article.Summary, article.Body, article.Lang = update.Title, update.Summary, update.Body, update.Lang
+	article.Tags, article.Phases, article.Conditions = update.Tags, update.Phases, update.Conditions
+	if err := models.UpdateArticle(models.GetDB(), article); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error updating article", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, article)
+}
+
+// PublishArticle publishes an article, or takes it back to draft when
+// publish is false.
+func PublishArticle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireNutritionist(w, r)
+	if !ok {
+		return
+	}
+
+	var req PublishArticleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+	article := getAuthoredArticle(w, req.ArticleID, userID)
+	if article == nil {
+		return
+	}
+
+	if err := models.SetArticlePublished(models.GetDB(), article, req.Publish); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error publishing article", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, article)
+}
+
+// ListMyArticles lists the authenticated nutritionist's articles, drafts
+// included.
+func ListMyArticles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireNutritionist(w, r)
+	if !ok {
+		return
+	}
+
+	articles, err := models.ListArticlesByAuthor(models.GetDB(), userID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving articles", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, articles)
+}
+
+// GetArticle returns a published article with its Markdown body.
+func GetArticle(w http.ResponseWriter, r *http.Request) {
+	articleID, err := strconv.Atoi(r.URL.Query().Get("article_id"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid article_id"})
+		return
+	}
+
+	article, err := models.GetArticle(models.GetDB(), articleID)
+	if err == models.ErrArticleNotFound || (err == nil && article.Status != models.ArticlePublished) {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": models.ErrArticleNotFound.Error()})
+		return
+	}
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving article", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, article)
+}
+
+// ListArticles lists published articles, filtered by ?tag, ?phase and
+// ?condition, in the request's language.
+func ListArticles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.ArticleFilter{
+		Lang:      locale.FromRequest(r),
+		Tag:       query.Get("tag"),
+		Phase:     query.Get("phase"),
+		Condition: query.Get("condition"),
+	}
+
+	articles, err := models.ListPublishedArticles(models.GetDB(), filter)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving articles", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, articles)
+}
@@ -0,0 +1,298 @@
+package artikel
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"nutrishe/auth"
+	"nutrishe/locale"
+	"nutrishe/models"
+	"nutrishe/search"
+)
+
+// Limits of a recommendation: how many topics are searched and how many
+// results are taken from each source per topic.
+const (
+	maxTopics        = 4
+	resultsPerTopic  = 5
+	inHouseBonus     = 1.5
+	readPenalty      = 0.3
+	readHistoryLimit = 100
+)
+
+// Weights of the reasons an article is recommended for. Nutrient gaps and
+// symptoms are the most pressing, the cycle phase applies to everyone.
+const (
+	weightGap     = 3.0
+	weightSymptom = 2.5
+	weightGoal    = 2.0
+	weightPhase   = 1.5
+)
+
+// phaseQueries, gapQueries and goalQueries are the searches for each part
+// of the user's state, by language.
+var phaseQueries = map[string]map[string]string{
+	models.PhaseMenstrual: {
+		locale.Indonesian: "makanan kaya zat besi saat menstruasi",
+		locale.English:    "iron rich foods during menstruation",
+	},
+	models.PhaseFollicular: {
+		locale.Indonesian: "nutrisi fase folikular siklus menstruasi",
+		locale.English:    "nutrition in the follicular phase of the menstrual cycle",
+	},
+	models.PhaseOvulation: {
+		locale.Indonesian: "makanan sehat saat masa ovulasi",
+		locale.English:    "healthy eating during ovulation",
+	},
+	models.PhaseLuteal: {
+		locale.Indonesian: "makanan untuk mengurangi gejala PMS fase luteal",
+		locale.English:    "foods to ease PMS in the luteal phase",
+	},
+}
+
+var gapQueries = map[string]map[string]string{
+	models.GapProtein: {
+		locale.Indonesian: "cara memenuhi kebutuhan protein harian wanita",
+		locale.English:    "how women can get enough protein every day",
+	},
+	models.GapFiber: {
+		locale.Indonesian: "makanan tinggi serat untuk pencernaan",
+		locale.English:    "high fiber foods for digestion",
+	},
+	models.GapIron: {
+		locale.Indonesian: "mencegah anemia dengan makanan kaya zat besi",
+		locale.English:    "preventing anemia with iron rich foods",
+	},
+	models.GapCaloriesLow: {
+		locale.Indonesian: "bahaya makan terlalu sedikit kalori",
+		locale.English:    "risks of eating too few calories",
+	},
+	models.GapCalories: {
+		locale.Indonesian: "tips mengontrol porsi makan",
+		locale.English:    "tips for portion control",
+	},
+}
+
+var goalQueries = map[string]map[string]string{
+	models.PlanGoalLose: {
+		locale.Indonesian: "diet sehat menurunkan berat badan wanita",
+		locale.English:    "healthy weight loss diet for women",
+	},
+	models.PlanGoalGain: {
+		locale.Indonesian: "menambah berat badan secara sehat",
+		locale.English:    "gaining weight in a healthy way",
+	},
+	models.PlanGoalMaintain: {
+		locale.Indonesian: "pola makan seimbang untuk menjaga berat badan",
+		locale.English:    "balanced diet to maintain weight",
+	},
+}
+
+// gapConditions and goalConditions pick the in-house articles labelled for
+// a condition instead of searching them.
+var gapConditions = map[string]string{
+	models.GapIron: "anemia",
+}
+
+var goalConditions = map[string]string{
+	models.PlanGoalLose: "weight_loss",
+}
+
+// topic is one search built from the user's state. Reason says which part
+// of the state it came from, e.g. "gap:iron_low".
+type topic struct {
+	Reason    string
+	Query     string
+	Weight    float64
+	Phase     string
+	Condition string
+}
+
+// RecommendedArticle is a search result with the reasons it was picked.
+type RecommendedArticle struct {
+	SearchItem
+	Reasons []string `json:"reasons"`
+	Score   float64  `json:"score"`
+}
+
+// buildTopics turns the user's state into searches, most important first.
+func buildTopics(ctx *models.ArticleContext, lang string) []topic {
+	var topics []topic
+	for _, gap := range ctx.Gaps {
+		if q, ok := gapQueries[gap.Gap]; ok {
+			topics = append(topics, topic{Reason: "gap:" + gap.Gap, Query: q[lang], Weight: weightGap, Condition: gapConditions[gap.Gap]})
+		}
+	}
+	for _, symptom := range ctx.Symptoms {
+		query := "makanan untuk meredakan " + symptom
+		if lang == locale.English {
+			query = "foods that relieve " + symptom
+		}
+		topics = append(topics, topic{Reason: "symptom:" + symptom, Query: query, Weight: weightSymptom})
+	}
+	if q, ok := goalQueries[ctx.PlanGoal]; ok {
+		topics = append(topics, topic{Reason: "goal:" + ctx.PlanGoal, Query: q[lang], Weight: weightGoal, Condition: goalConditions[ctx.PlanGoal]})
+	}
+	if q, ok := phaseQueries[ctx.CyclePhase]; ok {
+		topics = append(topics, topic{Reason: "phase:" + ctx.CyclePhase, Query: q[lang], Weight: weightPhase, Phase: ctx.CyclePhase})
+	}
+
+	// Without any state, fall back to general reading
+	if len(topics) == 0 {
+		topics = append(topics, topic{Reason: "general", Query: goalQueries[models.PlanGoalMaintain][lang], Weight: 1})
+	}
+	if len(topics) > maxTopics {
+		topics = topics[:maxTopics]
+	}
+	return topics
+}
+
+// topicResults are the results of one topic, best first.
+type topicResults struct {
+	items  []SearchItem
+	webErr error
+}
+
+// searchTopic looks up a topic in-house and on the web.
+func searchTopic(ctx context.Context, t topic, lang string) topicResults {
+	var res topicResults
+
+	var inHouse []models.Article
+	var err error
+	if t.Phase != "" || t.Condition != "" {
+		inHouse, err = models.ListPublishedArticles(models.GetDB(), models.ArticleFilter{Lang: lang, Phase: t.Phase, Condition: t.Condition})
+	} else {
+		inHouse, err = models.SearchPublishedArticles(models.GetDB(), t.Query, lang, resultsPerTopic)
+	}
+	if err != nil {
+		log.Printf("Error searching in-house articles for %s: %v", t.Reason, err)
+	}
+	for i, a := range inHouse {
+		if i == resultsPerTopic {
+			break
+		}
+		res.items = append(res.items, inHouseItem(a))
+	}
+
+	web, err := search.Get().Search(ctx, search.Query{Text: t.Query, Lang: lang, Limit: resultsPerTopic})
+	if err != nil {
+		log.Printf("Error searching the web for %s: %v", t.Reason, err)
+		res.webErr = err
+	}
+	for _, a := range web.Articles {
+		res.items = append(res.items, SearchItem{Article: a, Source: SourceWeb})
+	}
+	return res
+}
+
+// rankArticles merges the results of all topics. An article scores the
+// topic weight divided by its rank within its source, summed over topics;
+// in-house articles get a bonus and articles already read are pushed down.
+func rankArticles(topics []topic, results []topicResults, read map[string]bool) []RecommendedArticle {
+	byLink := make(map[string]*RecommendedArticle)
+	var order []string
+	for i, t := range topics {
+		ranks := make(map[string]int)
+		for _, item := range results[i].items {
+			rank := ranks[item.Source]
+			ranks[item.Source]++
+
+			score := t.Weight / float64(rank+1)
+			if item.Source == SourceInHouse {
+				score *= inHouseBonus
+			}
+
+			rec, ok := byLink[item.Link]
+			if !ok {
+				rec = &RecommendedArticle{SearchItem: item, Reasons: []string{}}
+				byLink[item.Link] = rec
+				order = append(order, item.Link)
+			}
+			rec.Score += score
+			if len(rec.Reasons) == 0 || rec.Reasons[len(rec.Reasons)-1] != t.Reason {
+				rec.Reasons = append(rec.Reasons, t.Reason)
+			}
+		}
+	}
+
+	ranked := make([]RecommendedArticle, 0, len(order))
+	for _, link := range order {
+		rec := byLink[link]
+		if read[link] {
+			rec.Score *= readPenalty
+		}
+		rec.Score = float64(int(rec.Score*100+0.5)) / 100
+		ranked = append(ranked, *rec)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	if len(ranked) > maxResults {
+		ranked = ranked[:maxResults]
+	}
+	return ranked
+}
+
+// RecommendArticles suggests articles for the authenticated user's current
+// cycle phase, recent symptoms, nutrient gaps and diet plan.
+func RecommendArticles(w http.ResponseWriter, r *http.Request) {
+	lang := locale.FromRequest(r)
+	w.Header().Set("Content-Language", lang)
+	userID := auth.CurrentUserID(r)
+
+	state, err := models.GetArticleContext(models.GetDB(), userID, time.Now())
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving user state", "error": err.Error()})
+		return
+	}
+
+	history, err := models.GetReadingHistory(models.GetDB(), userID, readHistoryLimit)
+	if err != nil {
+		// Recommendations still work without knowing what was read
+		log.Printf("Error retrieving reading history: %v", err)
+	}
+	read := make(map[string]bool, len(history))
+	for _, h := range history {
+		read[h.Link] = true
+	}
+
+	topics := buildTopics(state, lang)
+	results := make([]topicResults, len(topics))
+	var wg sync.WaitGroup
+	for i, t := range topics {
+		wg.Add(1)
+		go func(i int, t topic) {
+			defer wg.Done()
+			results[i] = searchTopic(r.Context(), t, lang)
+		}(i, t)
+	}
+	wg.Wait()
+
+	articles := rankArticles(topics, results, read)
+	if len(articles) == 0 {
+		for _, res := range results {
+			if res.webErr != nil {
+				respondJSON(w, http.StatusBadGateway, map[string]string{"message": locale.Message(lang, "search_failed"), "error": res.webErr.Error()})
+				return
+			}
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"context":  state,
+		"topics":   topicReasons(topics),
+		"articles": articles,
+	})
+}
+
+// topicReasons lists the reasons that were searched, so clients can
+// explain the recommendations.
+func topicReasons(topics []topic) []string {
+	reasons := make([]string, len(topics))
+	for i, t := range topics {
+		reasons[i] = t.Reason
+	}
+	return reasons
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"nutrishe/entity"
+	"nutrishe/locale"
 	"nutrishe/models"
 )
 
@@ -33,6 +34,24 @@ func AddMeal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data.Tags, err = models.NormalizeFoodTags(data.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for lang := range data.Names {
+		if !locale.IsSupported(lang) {
+			http.Error(w, "Unsupported language: "+lang, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if data.PricePerServing != nil && *data.PricePerServing < 0 {
+		http.Error(w, "PricePerServing must not be negative", http.StatusBadRequest)
+		return
+	}
+
 	foodID := generateFoodID()
 
 	data.FoodID = foodID
@@ -48,3 +67,71 @@ func AddMeal(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusCreated)
 }
+
+// UpdateFoodTags replaces the allergen and diet tags of an existing food
+func UpdateFoodTags(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		FoodID string   `json:"FoodID"`
+		Tags   []string `json:"Tags"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if data.FoodID == "" {
+		http.Error(w, "Missing FoodID", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := models.NormalizeFoodTags(data.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	db := models.GetDB()
+	err = models.SetFoodTags(db, data.FoodID, tags)
+	if err != nil {
+		http.Error(w, "Failed to update food tags: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"FoodID": data.FoodID, "Tags": tags})
+}
+
+// UpdateFoodPrice sets the price per serving of an existing food, in rupiah
+func UpdateFoodPrice(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		FoodID          string `json:"FoodID"`
+		PricePerServing int    `json:"PricePerServing"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if data.FoodID == "" {
+		http.Error(w, "Missing FoodID", http.StatusBadRequest)
+		return
+	}
+	if data.PricePerServing < 0 {
+		http.Error(w, "PricePerServing must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	db := models.GetDB()
+	err = models.SetFoodPrice(db, data.FoodID, data.PricePerServing)
+	if err == models.ErrFoodNotFound {
+		http.Error(w, "Food not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update food price: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"FoodID": data.FoodID, "PricePerServing": data.PricePerServing})
+}
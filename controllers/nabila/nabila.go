@@ -2,14 +2,14 @@ package nabila
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"nutrishe/auth"
 	"nutrishe/models"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
 
 	"crypto/rand"
@@ -37,28 +37,26 @@ type RegisterCredentials struct {
 	Weight    float32 `json:"weight"`
 }
 
-// Claims represents the JWT claims
-type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	jwt.StandardClaims
-}
-
-var jwtKey = []byte(os.Getenv("JWT_KEY"))
-
 // DailyLogRequest represents the request payload for the daily log
 type DailyLogRequest struct {
 	UserID     string `json:"user_id"`
 	SymptomsID string `json:"symptoms_id"`
 }
 
-// CreateDietPlanRequest represents the request payload for creating a diet plan
+// CreateDietPlanRequest represents the request payload for creating or
+// updating a diet plan. Dates use the YYYY-MM-DD format.
 type CreateDietPlanRequest struct {
-	PlanID      string    `json:"plan_id"`
-	UserID      string    `json:"user_id"`
-	StartDate   time.Time `json:"start_date"`
-	EndDate     time.Time `json:"end_date"`
-	CalorieGoal int       `json:"calorie_goal"`
+	PlanID      string `json:"plan_id"`
+	UserID      string `json:"user_id"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	CalorieGoal int    `json:"calorie_goal"`
+}
+
+// DietPlanResponse is a diet plan together with its status for today
+type DietPlanResponse struct {
+	models.DietPlan
+	Status string `json:"status"`
 }
 
 // Register handles user registration
@@ -112,13 +110,6 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID:   user.UserID,
-		Username: user.Username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
-	}
 
 	// Include additional user data in response
 	response := map[string]interface{}{
@@ -132,8 +123,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		"token":     "", // Placeholder for token
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := auth.NewToken(user.UserID, user.Username, expirationTime)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -170,30 +160,6 @@ func generateID() string {
 	return fmt.Sprintf("US%03d", bytes[0]<<8|bytes[1])
 }
 
-func JWTMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			http.Error(w, "Missing token", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 	w.Header().Set("Content-Type", "application/json")
@@ -201,7 +167,59 @@ func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Write(response)
 }
 
-// CreateDietPlan handles creating a new diet plan
+// parseDate parses a YYYY-MM-DD date, returning fallback when value is empty.
+// Older clients send RFC 3339 timestamps, of which only the date is kept.
+// Their zero time means the date was not set.
+func parseDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" || value == "0001-01-01T00:00:00Z" {
+		return fallback, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// respondPlanError maps diet plan errors to HTTP responses
+func respondPlanError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case err == models.ErrPlanOverlap:
+		respondJSON(w, http.StatusConflict, map[string]string{"message": message, "error": err.Error()})
+	case err == models.ErrPlanNotFound:
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": message, "error": err.Error()})
+	case errors.Is(err, models.ErrIDsExhausted):
+		respondJSON(w, http.StatusServiceUnavailable, map[string]string{"message": message, "error": "no more diet plans can be created"})
+	default:
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": message, "error": err.Error()})
+	}
+}
+
+// getOwnedPlan loads a plan and checks that it belongs to userID
+func getOwnedPlan(w http.ResponseWriter, planID, userID string) *models.DietPlan {
+	if planID == "" || userID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "PlanID and UserID are required"})
+		return nil
+	}
+
+	plan, err := models.GetDietPlan(models.GetDB(), planID)
+	if err != nil {
+		respondPlanError(w, "Error retrieving diet plan", err)
+		return nil
+	}
+	if plan.UserID != userID {
+		respondPlanError(w, "Error retrieving diet plan", models.ErrPlanNotFound)
+		return nil
+	}
+	return plan
+}
+
+// CreateDietPlan handles creating a new diet plan. The start date defaults
+// to today and the end date to one month after the start.
 func CreateDietPlan(w http.ResponseWriter, r *http.Request) {
 	var req CreateDietPlanRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -214,23 +232,224 @@ func CreateDietPlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.CalorieGoal <= 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "CalorieGoal must be positive"})
+		return
+	}
+
+	startDate, err := parseDate(req.StartDate, today())
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid start_date, please use YYYY-MM-DD"})
+		return
+	}
+	endDate, err := parseDate(req.EndDate, startDate.AddDate(0, 1, 0))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid end_date, please use YYYY-MM-DD"})
+		return
+	}
+	if endDate.Before(startDate) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "end_date must not be before start_date"})
+		return
+	}
+
 	dietPlan := models.DietPlan{
-		PlanID:      generateID(), // Assume a function to generate unique IDs
 		UserID:      req.UserID,
-		StartDate:   time.Now(),
-		EndDate:     time.Now().AddDate(0, 1, 0), // Assuming a default one month duration
+		StartDate:   startDate,
+		EndDate:     endDate,
 		CalorieGoal: req.CalorieGoal,
 	}
 
 	db := models.GetDB()
-	query := "INSERT INTO diet_plan (PlanID, UserID, StartDate, EndDate, CalorieGoal) VALUES (?, ?, ?, ?, ?)"
-	_, err := db.Exec(query, dietPlan.PlanID, dietPlan.UserID, dietPlan.StartDate, dietPlan.EndDate, dietPlan.CalorieGoal)
+	if err := models.CreateDietPlan(db, &dietPlan); err != nil {
+		respondPlanError(w, "Error creating diet plan", err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, DietPlanResponse{dietPlan, dietPlan.Status(today())})
+}
+
+// ListDietPlans handles viewing the current plan and the history of plans
+func ListDietPlans(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+
+	db := models.GetDB()
+	plans, err := models.ListDietPlans(db, userID)
 	if err != nil {
-		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error creating diet plan", "error": err.Error()})
+		respondPlanError(w, "Error retrieving diet plans", err)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, dietPlan)
+	var current *DietPlanResponse
+	upcoming := []DietPlanResponse{}
+	history := []DietPlanResponse{}
+	for _, plan := range plans {
+		resp := DietPlanResponse{plan, plan.Status(today())}
+		switch resp.Status {
+		case models.PlanActive:
+			current = &resp
+		case models.PlanUpcoming:
+			upcoming = append(upcoming, resp)
+		default:
+			history = append(history, resp)
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"current":  current,
+		"upcoming": upcoming,
+		"history":  history,
+	})
+}
+
+// UpdateDietPlan handles changing the dates or calorie goal of a plan.
+// Fields left empty keep their current value.
+func UpdateDietPlan(w http.ResponseWriter, r *http.Request) {
+	var req CreateDietPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	plan := getOwnedPlan(w, req.PlanID, req.UserID)
+	if plan == nil {
+		return
+	}
+
+	startDate, err := parseDate(req.StartDate, plan.StartDate)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid start_date, please use YYYY-MM-DD"})
+		return
+	}
+	endDate, err := parseDate(req.EndDate, plan.EndDate)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid end_date, please use YYYY-MM-DD"})
+		return
+	}
+	if endDate.Before(startDate) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "end_date must not be before start_date"})
+		return
+	}
+
+	plan.StartDate = startDate
+	plan.EndDate = endDate
+	if req.CalorieGoal > 0 {
+		plan.CalorieGoal = req.CalorieGoal
+	}
+
+	if err := models.UpdateDietPlan(models.GetDB(), *plan); err != nil {
+		respondPlanError(w, "Error updating diet plan", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DietPlanResponse{*plan, plan.Status(today())})
+}
+
+// EndDietPlan handles ending an active plan early. The plan ends on
+// end_date, or today when it is not given.
+func EndDietPlan(w http.ResponseWriter, r *http.Request) {
+	var req CreateDietPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	plan := getOwnedPlan(w, req.PlanID, req.UserID)
+	if plan == nil {
+		return
+	}
+
+	endDate, err := parseDate(req.EndDate, today())
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid end_date, please use YYYY-MM-DD"})
+		return
+	}
+	if endDate.Before(plan.StartDate) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Plan has not started yet, delete it instead"})
+		return
+	}
+	if endDate.After(plan.EndDate) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "end_date is after the planned end date"})
+		return
+	}
+
+	plan.EndDate = endDate
+	if err := models.UpdateDietPlan(models.GetDB(), *plan); err != nil {
+		respondPlanError(w, "Error ending diet plan", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DietPlanResponse{*plan, plan.Status(today())})
+}
+
+// DeleteDietPlan handles deleting a plan
+func DeleteDietPlan(w http.ResponseWriter, r *http.Request) {
+	var req CreateDietPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	plan := getOwnedPlan(w, req.PlanID, req.UserID)
+	if plan == nil {
+		return
+	}
+
+	if err := models.DeleteDietPlan(models.GetDB(), plan.PlanID); err != nil {
+		respondPlanError(w, "Error deleting diet plan", err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Diet plan deleted successfully"})
+}
+
+// ViewDietPlanProgress handles comparing the current plan (or the plan
+// given by plan_id) with the intake logged since it started
+func ViewDietPlanProgress(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	planID := r.URL.Query().Get("plan_id")
+	if userID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+
+	db := models.GetDB()
+	var plan *models.DietPlan
+	if planID != "" {
+		plan = getOwnedPlan(w, planID, userID)
+		if plan == nil {
+			return
+		}
+	} else {
+		var err error
+		plan, err = models.GetCurrentDietPlan(db, userID, today())
+		if err != nil {
+			respondPlanError(w, "Error retrieving diet plan", err)
+			return
+		}
+		if plan == nil {
+			respondJSON(w, http.StatusNotFound, map[string]string{"message": "No active diet plan"})
+			return
+		}
+	}
+
+	days, err := models.GetDailyIntake(db, userID, plan.StartDate, plan.EndDate)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving daily intake", "error": err.Error()})
+		return
+	}
+
+	// The latest calorie calculation is used as the user's expenditure
+	tdee, err := models.GetLatestCalorieRequirement(db, userID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving calorie data", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.ComputePlanProgress(*plan, days, today(), tdee))
 }
 
 // CalculateCalories handles calculating the calories for a given date
@@ -332,23 +551,24 @@ func GetCalorieDataHandler(w http.ResponseWriter, r *http.Request) {
 
 // ViewCaloriesGoal handles viewing the calorie goal for a user
 func ViewCaloriesGoal(w http.ResponseWriter, r *http.Request) {
-	// userID := r.URL.Query().Get("user_id")
-	userID := "US001"
+	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
 		return
 	}
 
-	var calorieGoal int
 	db := models.GetDB()
-	query := "SELECT CalorieGoal FROM diet_plan WHERE UserID = ? ORDER BY EndDate DESC LIMIT 1"
-	err := db.QueryRow(query, userID).Scan(&calorieGoal)
+	plan, err := models.GetCurrentDietPlan(db, userID, today())
 	if err != nil {
 		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving diet plan", "error": err.Error()})
 		return
 	}
+	if plan == nil {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": "No active diet plan"})
+		return
+	}
 
-	respondJSON(w, http.StatusOK, map[string]int{"calorie_goal": calorieGoal})
+	respondJSON(w, http.StatusOK, map[string]interface{}{"plan_id": plan.PlanID, "calorie_goal": plan.CalorieGoal})
 }
 
 // ViewMonthlyCalories handles viewing the total calories for the current month
@@ -359,13 +579,14 @@ func ViewMonthlyCalories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	startOfMonth := time.Now().AddDate(0, 0, -time.Now().Day()+1)
+	now := time.Now()
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 	endOfMonth := startOfMonth.AddDate(0, 1, -1)
 
 	var totalCalories int
 	db := models.GetDB()
-	query := "SELECT SUM(total_calories) FROM daily_meal WHERE user_id = ? AND meal_date BETWEEN ? AND ?"
-	err := db.QueryRow(query, userID, startOfMonth, endOfMonth).Scan(&totalCalories)
+	query := "SELECT COALESCE(SUM(TotalCalories), 0) FROM daily_meal WHERE UserID = ? AND MealDate BETWEEN ? AND ?"
+	err := db.QueryRow(query, userID, startOfMonth.Format("2006-01-02"), endOfMonth.Format("2006-01-02")).Scan(&totalCalories)
 	if err != nil {
 		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error calculating monthly calories", "error": err.Error()})
 		return
@@ -373,3 +594,107 @@ func ViewMonthlyCalories(w http.ResponseWriter, r *http.Request) {
 
 	respondJSON(w, http.StatusOK, map[string]int{"total_calories": totalCalories})
 }
+
+// ViewNutritionSummary handles viewing calorie and macro summaries for an
+// arbitrary date range, grouped in daily, weekly or monthly buckets
+func ViewNutritionSummary(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID := query.Get("user_id")
+	if userID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+
+	// Default to the last 30 days
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid to date, please use YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := query.Get("from"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid from date, please use YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "from must not be after to"})
+		return
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Range must not exceed one year"})
+		return
+	}
+
+	bucket := query.Get("bucket")
+	switch bucket {
+	case "":
+		bucket = models.BucketDaily
+	case models.BucketDaily, models.BucketWeekly, models.BucketMonthly:
+	default:
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid bucket, use daily, weekly or monthly"})
+		return
+	}
+
+	db := models.GetDB()
+	days, err := models.GetDailyIntake(db, userID, from, to)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving daily intake", "error": err.Error()})
+		return
+	}
+
+	plans, err := models.GetDietPlansInRange(db, userID, from, to)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving diet plans", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.SummarizeIntake(userID, days, plans, bucket, from, to))
+}
+
+// GetPreferences handles viewing the allergies and diets of a user
+func GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+
+	db := models.GetDB()
+	prefs, err := models.GetUserPreferences(db, userID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving preferences", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, prefs)
+}
+
+// UpdatePreferences handles replacing the allergies and diets of a user
+func UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req models.UserPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	if req.UserID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+
+	db := models.GetDB()
+	if err := models.SaveUserPreferences(db, &req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Error saving preferences", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, req)
+}
@@ -0,0 +1,159 @@
+package nabila
+
+import (
+	"encoding/json"
+	"net/http"
+	"nutrishe/auth"
+	"nutrishe/models"
+	"strconv"
+)
+
+// AdoptTemplateRequest represents the request payload for creating a diet
+// plan from a template. CalorieGoal overrides the template's goal.
+type AdoptTemplateRequest struct {
+	UserID      string `json:"user_id"`
+	TemplateID  int    `json:"template_id"`
+	StartDate   string `json:"start_date"`
+	CalorieGoal int    `json:"calorie_goal"`
+}
+
+// ListPlanTemplates handles viewing all diet plan templates
+func ListPlanTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := models.ListDietPlanTemplates(models.GetDB())
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving templates", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, templates)
+}
+
+// GetPlanTemplate handles viewing a single diet plan template
+func GetPlanTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := strconv.Atoi(r.URL.Query().Get("template_id"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid template_id"})
+		return
+	}
+
+	template, err := models.GetDietPlanTemplate(models.GetDB(), templateID)
+	if err == models.ErrTemplateNotFound {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving template", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, template)
+}
+
+// CreatePlanTemplate handles publishing a new template. Only nutritionists
+// may create templates, so the route must go through JWTMiddleware.
+func CreatePlanTemplate(w http.ResponseWriter, r *http.Request) {
+	db := models.GetDB()
+	userID := auth.CurrentUserID(r)
+	ok, err := models.IsNutritionist(db, userID)
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error checking role", "error": err.Error()})
+		return
+	}
+	if !ok {
+		respondJSON(w, http.StatusForbidden, map[string]string{"message": "Only nutritionists can create templates"})
+		return
+	}
+
+	var req models.DietPlanTemplate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	if req.Name == "" || req.CalorieGoal <= 0 || req.DurationDays <= 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Name, calorie_goal and duration_days are required"})
+		return
+	}
+	if req.ProteinPercent < 0 || req.CarbohydratePercent < 0 || req.FatPercent < 0 ||
+		req.ProteinPercent+req.CarbohydratePercent+req.FatPercent != 100 {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Macro percentages must add up to 100"})
+		return
+	}
+	if req.Foods == nil {
+		req.Foods = []string{}
+	}
+
+	req.AuthorID = userID
+	if err := models.CreateDietPlanTemplate(db, &req); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error creating template", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, req)
+}
+
+// AdoptPlanTemplate handles creating a user's diet plan from a template,
+// with macro targets personalised to the user's calorie goal
+func AdoptPlanTemplate(w http.ResponseWriter, r *http.Request) {
+	var req AdoptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Bad request"})
+		return
+	}
+
+	if req.UserID == "" {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "UserID is required"})
+		return
+	}
+	if req.CalorieGoal < 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "CalorieGoal must be positive"})
+		return
+	}
+
+	db := models.GetDB()
+	template, err := models.GetDietPlanTemplate(db, req.TemplateID)
+	if err == models.ErrTemplateNotFound {
+		respondJSON(w, http.StatusNotFound, map[string]string{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error retrieving template", "error": err.Error()})
+		return
+	}
+
+	startDate, err := parseDate(req.StartDate, today())
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid start_date, please use YYYY-MM-DD"})
+		return
+	}
+
+	calorieGoal := template.CalorieGoal
+	if req.CalorieGoal > 0 {
+		calorieGoal = req.CalorieGoal
+	}
+
+	plan := models.DietPlan{
+		UserID:      req.UserID,
+		StartDate:   startDate,
+		EndDate:     startDate.AddDate(0, 0, template.DurationDays-1),
+		CalorieGoal: calorieGoal,
+	}
+	if err := models.CreateDietPlan(db, &plan); err != nil {
+		respondPlanError(w, "Error creating diet plan", err)
+		return
+	}
+
+	target := template.MacroTargets(calorieGoal)
+	target.PlanID = plan.PlanID
+	if err := models.SaveDietPlanTarget(db, target); err != nil {
+		respondJSON(w, http.StatusInternalServerError, map[string]string{"message": "Error saving plan targets", "error": err.Error()})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"plan":     DietPlanResponse{plan, plan.Status(today())},
+		"targets":  target,
+		"guidance": template.Guidance,
+		"foods":    template.Foods,
+	})
+}
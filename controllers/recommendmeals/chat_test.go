@@ -0,0 +1,183 @@
+package recommendmeals
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nutrishe/auth"
+	"nutrishe/llm"
+	"nutrishe/models/dbtest"
+)
+
+// recordingProvider keeps the requests that reach the provider.
+type recordingProvider struct {
+	*llm.Fake
+	requests []llm.Request
+}
+
+func (p *recordingProvider) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.requests = append(p.requests, req)
+	return p.Fake.Generate(ctx, req)
+}
+
+// chatDB answers as if thread 1 of owner held messages earlier messages
+// and the user had made used AI requests.
+func chatDB(owner string, messages, used int) dbtest.Responder {
+	return func(query string, args []driver.Value) ([][]driver.Value, error) {
+		switch {
+		case strings.Contains(query, "FROM chat_thread WHERE ThreadID"):
+			if args[0] == int64(1) {
+				return [][]driver.Value{{int64(1), owner, "Menu diet", "2026-01-02 08:00:00", "2026-01-02 08:00:00"}}, nil
+			}
+		case strings.Contains(query, "FROM chat_message WHERE ThreadID"):
+			var rows [][]driver.Value
+			for i := 1; i <= messages; i++ {
+				role := llm.RoleUser
+				if i%2 == 0 {
+					role = llm.RoleModel
+				}
+				rows = append(rows, []driver.Value{int64(i), role, fmt.Sprintf("message %d", i), "2026-01-02 08:00:00"})
+			}
+			return rows, nil
+		case strings.Contains(query, "FROM ai_usage"):
+			return [][]driver.Value{{int64(used), int64(0), int64(0), int64(0)}}, nil
+		}
+		return nil, nil
+	}
+}
+
+func chatRequest(threadID int) *http.Request {
+	body := fmt.Sprintf(`{"thread_id":%d,"message":"Ganti makan siang dong"}`, threadID)
+	r := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body))
+	return auth.WithUserID(r, "US001")
+}
+
+func TestChat(t *testing.T) {
+	tests := []struct {
+		name        string
+		threadID    int
+		owner       string
+		used        int
+		err         error
+		wantStatus  int
+		wantCalls   int
+		wantSaved   bool
+		wantCreated bool
+	}{
+		{"new thread", 0, "US001", 0, nil, http.StatusOK, 1, true, true},
+		{"existing thread", 1, "US001", 0, nil, http.StatusOK, 1, true, false},
+		{"another user's thread", 1, "US002", 0, nil, http.StatusNotFound, 0, false, false},
+		{"missing thread", 2, "US001", 0, nil, http.StatusNotFound, 0, false, false},
+		{"quota used up", 0, "US001", 20, nil, http.StatusTooManyRequests, 0, false, false},
+		{"provider fails", 1, "US001", 0, errors.New("provider unreachable"), http.StatusBadGateway, 1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{Fake: &llm.Fake{Err: tt.err}}
+			db := setup(t, provider, chatDB(tt.owner, 2, tt.used))
+
+			w := httptest.NewRecorder()
+			Chat(w, chatRequest(tt.threadID))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if len(provider.requests) != tt.wantCalls {
+				t.Errorf("provider calls = %d, want %d", len(provider.requests), tt.wantCalls)
+			}
+			if got := db.Ran("INSERT INTO chat_message"); got != tt.wantSaved {
+				t.Errorf("messages saved = %v, want %v", got, tt.wantSaved)
+			}
+			if got := db.Ran("INSERT INTO chat_thread"); got != tt.wantCreated {
+				t.Errorf("thread created = %v, want %v", got, tt.wantCreated)
+			}
+			if tt.err != nil && !db.Ran("DELETE FROM ai_usage") {
+				t.Error("failed request was not given back")
+			}
+		})
+	}
+}
+
+func TestChatSavesThreadWithMessages(t *testing.T) {
+	db := setup(t, &llm.Fake{}, chatDB("US001", 0, 0))
+
+	w := httptest.NewRecorder()
+	Chat(w, chatRequest(0))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// The thread and both messages are written in one transaction
+	var saved []string
+	inTx := false
+	for _, s := range db.Statements() {
+		switch {
+		case s == "BEGIN":
+			inTx, saved = true, nil
+		case s == "COMMIT":
+			inTx = false
+		case inTx && strings.Contains(s, "chat_"):
+			saved = append(saved, strings.Join(strings.Fields(s)[:3], " "))
+		}
+	}
+	want := []string{"INSERT INTO chat_thread", "INSERT INTO chat_message", "INSERT INTO chat_message", "UPDATE chat_thread SET"}
+	if strings.Join(saved, ", ") != strings.Join(want, ", ") {
+		t.Errorf("last transaction = %v, want %v", saved, want)
+	}
+}
+
+func TestChatHistoryLimit(t *testing.T) {
+	provider := &recordingProvider{Fake: &llm.Fake{}}
+	setup(t, provider, chatDB("US001", chatHistoryLimit+5, 0))
+
+	w := httptest.NewRecorder()
+	Chat(w, chatRequest(1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	history := provider.requests[0].History
+	if len(history) != chatHistoryLimit {
+		t.Fatalf("history has %d messages, want %d", len(history), chatHistoryLimit)
+	}
+	if history[0].Text != "message 6" || history[len(history)-1].Text != fmt.Sprintf("message %d", chatHistoryLimit+5) {
+		t.Errorf("history = %q ... %q, want the latest messages", history[0].Text, history[len(history)-1].Text)
+	}
+}
+
+func TestChatThreadOwnership(t *testing.T) {
+	handlers := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"get", GetChatThread},
+		{"delete", DeleteChatThread},
+	}
+	tests := []struct {
+		owner      string
+		wantStatus int
+	}{
+		{"US001", http.StatusOK},
+		{"US002", http.StatusNotFound},
+	}
+	for _, h := range handlers {
+		for _, tt := range tests {
+			db := setup(t, &llm.Fake{}, chatDB(tt.owner, 2, 0))
+
+			w := httptest.NewRecorder()
+			r := auth.WithUserID(httptest.NewRequest(http.MethodGet, "/chat/thread?thread_id=1", nil), "US001")
+			h.handler(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s thread of %s: status = %d, want %d", h.name, tt.owner, w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusNotFound && db.Ran("DELETE") {
+				t.Errorf("%s thread of %s: deleted another user's thread", h.name, tt.owner)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package recommendmeals
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+// maxCatalogueHints limits how many catalogue names are put in the prompt.
+const maxCatalogueHints = 150
+
+// catalogueHint asks the model to prefer dishes that exist in the catalogue
+// so that the plan can be logged.
+// With prices set, known prices are listed to help keep to a budget.
+func catalogueHint(catalogue []models.CatalogueFood, prices bool) string {
+	if len(catalogue) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, maxCatalogueHints)
+	for i := 0; i < len(catalogue) && i < maxCatalogueHints; i++ {
+		name := sanitize(catalogue[i].Name)
+		if prices && catalogue[i].Price != nil {
+			name = fmt.Sprintf("%s (Rp %d)", name, *catalogue[i].Price)
+		}
+		names = append(names, name)
+	}
+	return " Prefer dishes from this list and use their exact names: " + strings.Join(names, "; ") + "."
+}
+
+// groundMealPlan links every item to a catalogue food. Items with a close
+// enough name are matched, items with a weaker name but similar calories are
+// replaced by the catalogue food, and everything else is flagged unknown.
+// It returns the dietary conflicts of the catalogue foods used.
+func groundMealPlan(plan *entity.MealPlan, catalogue []models.CatalogueFood, prefs *models.UserPreferences) []models.TagConflict {
+	conflicts := []models.TagConflict{}
+	for d := range plan.Days {
+		for m := range plan.Days[d].Meals {
+			items := plan.Days[d].Meals[m].Items
+			for i := range items {
+				item := &items[i]
+				match := models.MatchFood(catalogue, item.Name, item.Calories)
+				item.Match = match.Kind
+				item.MatchScore = match.Score
+				if match.Food == nil {
+					continue
+				}
+
+				item.FoodID = match.Food.FoodID
+				if match.Kind == models.MatchSubstituted {
+					item.GeneratedName = item.Name
+					item.Name = match.Food.Name
+				}
+				conflicts = append(conflicts, models.FoodConflictsFor(match.Food.FoodID, match.Food.Tags, prefs)...)
+			}
+		}
+	}
+	return conflicts
+}
+
+// linkFood matches a recognised food to the catalogue and returns the
+// dietary conflicts of the name and of the matched food. calories is per
+// serving, or zero when unknown.
+func linkFood(catalogue []models.CatalogueFood, prefs *models.UserPreferences, name string, calories int) (models.FoodMatch, []models.TagConflict) {
+	conflicts := models.TextConflicts(name, prefs)
+	match := models.MatchFood(catalogue, name, calories)
+	if match.Food != nil {
+		conflicts = append(conflicts, models.FoodConflictsFor(match.Food.FoodID, match.Food.Tags, prefs)...)
+	}
+	return match, conflicts
+}
+
+// estimateCosts sets the price of every item, preferring the catalogue
+// price of matched foods, scaled to the item's portion, over the model's
+// estimate, and totals each day. Items without either price are listed in
+// the day's UnpricedItems.
+func estimateCosts(plan *entity.MealPlan, catalogue []models.CatalogueFood) {
+	foods := make(map[string]*models.CatalogueFood)
+	for i := range catalogue {
+		if catalogue[i].Price != nil {
+			foods[catalogue[i].FoodID] = &catalogue[i]
+		}
+	}
+
+	for d := range plan.Days {
+		day := &plan.Days[d]
+		day.EstimatedCost = 0
+		day.CostIncomplete, day.UnpricedItems = false, nil
+		for m := range day.Meals {
+			for i := range day.Meals[m].Items {
+				item := &day.Meals[m].Items[i]
+				if food, ok := foods[item.FoodID]; ok && item.FoodID != "" {
+					item.Price = int(math.Round(float64(*food.Price) * models.PortionServings(item.Portion, food.Serving)))
+				}
+				if item.Price <= 0 {
+					item.Price = 0
+					day.CostIncomplete = true
+					day.UnpricedItems = append(day.UnpricedItems, item.Name)
+				}
+				day.EstimatedCost += item.Price
+			}
+		}
+	}
+}
+
+// budgetErrors lists the days that cost more than budget; zero means no
+// cap. Unpriced items are not counted, see CostIncomplete.
+func budgetErrors(plan *entity.MealPlan, budget int) []string {
+	if budget <= 0 {
+		return nil
+	}
+	var errs []string
+	for _, day := range plan.Days {
+		if day.EstimatedCost > budget {
+			errs = append(errs, fmt.Sprintf("day %d costs Rp %d, above the daily budget of Rp %d", day.Day, day.EstimatedCost, budget))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,115 @@
+package recommendmeals
+
+import (
+	"reflect"
+	"testing"
+
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+func TestEstimateCosts(t *testing.T) {
+	price := func(p int) *int { return &p }
+	catalogue := []models.CatalogueFood{
+		{FoodID: "FD001", Name: "Nasi Putih", Serving: 100, Price: price(3000)},
+		{FoodID: "FD002", Name: "Tempe Goreng", Serving: 50, Price: price(2000)},
+		{FoodID: "FD003", Name: "Sayur Asem", Serving: 200},
+	}
+
+	tests := []struct {
+		name  string
+		items []entity.MealItem
+		want  []int
+		cost  int
+	}{
+		{
+			name:  "catalogue price per serving",
+			items: []entity.MealItem{{FoodID: "FD001", Portion: "1 plate", Price: 9999}},
+			want:  []int{3000},
+			cost:  3000,
+		},
+		{
+			name:  "scaled by counted portion",
+			items: []entity.MealItem{{FoodID: "FD002", Portion: "3 pieces"}},
+			want:  []int{6000},
+			cost:  6000,
+		},
+		{
+			name:  "scaled by weight",
+			items: []entity.MealItem{{FoodID: "FD001", Portion: "150 g"}},
+			want:  []int{4500},
+			cost:  4500,
+		},
+		{
+			name:  "model estimate kept without a catalogue price",
+			items: []entity.MealItem{{FoodID: "FD003", Portion: "1 bowl", Price: 5000}, {Name: "Kerupuk", Portion: "1", Price: -10}},
+			want:  []int{5000, 0},
+			cost:  5000,
+		},
+	}
+	for _, tt := range tests {
+		plan := &entity.MealPlan{Days: []entity.MealPlanDay{{Day: 1, Meals: []entity.Meal{{Name: "lunch", Items: tt.items}}}}}
+		estimateCosts(plan, catalogue)
+
+		var got []int
+		for _, item := range plan.Days[0].Meals[0].Items {
+			got = append(got, item.Price)
+		}
+		if !reflect.DeepEqual(got, tt.want) || plan.Days[0].EstimatedCost != tt.cost {
+			t.Errorf("%s: prices %v cost %d, want %v cost %d", tt.name, got, plan.Days[0].EstimatedCost, tt.want, tt.cost)
+		}
+	}
+}
+
+func TestUnpricedItems(t *testing.T) {
+	price := 10000
+	catalogue := []models.CatalogueFood{{FoodID: "FD001", Name: "Ayam Bakar", Serving: 100, Price: &price}}
+	plan := &entity.MealPlan{Days: []entity.MealPlanDay{
+		{Day: 1, Meals: []entity.Meal{{Name: "lunch", Items: []entity.MealItem{{Name: "Ayam Bakar", FoodID: "FD001", Portion: "1 piece"}, {Name: "Rendang", Portion: "1 piece"}}}}},
+		{Day: 2, Meals: []entity.Meal{{Name: "lunch", Items: []entity.MealItem{{Name: "Ayam Bakar", FoodID: "FD001", Portion: "1 piece"}}}}},
+	}}
+	estimateCosts(plan, catalogue)
+
+	// Day 1 looks within budget, but only because Rendang has no price
+	if errs := budgetErrors(plan, 15000); len(errs) != 0 {
+		t.Errorf("budgetErrors = %q, want none", errs)
+	}
+	tests := []struct {
+		incomplete bool
+		unpriced   []string
+	}{
+		{true, []string{"Rendang"}},
+		{false, nil},
+	}
+	for i, tt := range tests {
+		day := plan.Days[i]
+		if day.CostIncomplete != tt.incomplete || !reflect.DeepEqual(day.UnpricedItems, tt.unpriced) {
+			t.Errorf("day %d: CostIncomplete %v UnpricedItems %q, want %v %q", day.Day, day.CostIncomplete, day.UnpricedItems, tt.incomplete, tt.unpriced)
+		}
+	}
+
+	// Estimating again does not list items twice
+	estimateCosts(plan, catalogue)
+	if len(plan.Days[0].UnpricedItems) != 1 {
+		t.Errorf("UnpricedItems = %q after a second estimate", plan.Days[0].UnpricedItems)
+	}
+}
+
+func TestBudgetErrors(t *testing.T) {
+	plan := &entity.MealPlan{Days: []entity.MealPlanDay{{Day: 1, EstimatedCost: 40000}, {Day: 2, EstimatedCost: 60000}}}
+
+	tests := []struct {
+		budget int
+		want   int
+	}{
+		{0, 0},
+		{50000, 1},
+		{30000, 2},
+		{60000, 0},
+	}
+	for _, tt := range tests {
+		if got := budgetErrors(plan, tt.budget); len(got) != tt.want {
+			t.Errorf("budgetErrors(%d) = %q, want %d errors", tt.budget, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package recommendmeals
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"nutrishe/entity"
+	"nutrishe/llm"
+)
+
+// mealPlanSchema mirrors entity.MealPlan so the model answers with JSON
+// that can be decoded directly.
+var mealPlanSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"Days": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"Day":           {Type: llm.TypeInteger},
+					"TotalCalories": {Type: llm.TypeInteger},
+					"Meals": {
+						Type: llm.TypeArray,
+						Items: &llm.Schema{
+							Type: llm.TypeObject,
+							Properties: map[string]*llm.Schema{
+								"Name":          {Type: llm.TypeString, Description: "breakfast, lunch, dinner or snack"},
+								"TotalCalories": {Type: llm.TypeInteger},
+								"Items": {
+									Type: llm.TypeArray,
+									Items: &llm.Schema{
+										Type: llm.TypeObject,
+										Properties: map[string]*llm.Schema{
+											"Name":          {Type: llm.TypeString},
+											"Portion":       {Type: llm.TypeString, Description: "e.g. 1 cup, 150 g"},
+											"Calories":      {Type: llm.TypeInteger},
+											"Protein":       {Type: llm.TypeNumber, Description: "grams"},
+											"Carbohydrates": {Type: llm.TypeNumber, Description: "grams"},
+											"Fat":           {Type: llm.TypeNumber, Description: "grams"},
+											"Price":         {Type: llm.TypeInteger, Description: "estimated cost of the portion in Indonesian rupiah"},
+											"Ingredients": {
+												Type:        llm.TypeArray,
+												Description: "raw ingredients to buy for this item",
+												Items: &llm.Schema{
+													Type: llm.TypeObject,
+													Properties: map[string]*llm.Schema{
+														"Name":     {Type: llm.TypeString},
+														"Quantity": {Type: llm.TypeNumber},
+														"Unit":     {Type: llm.TypeString, Description: "g, ml, piece, tablespoon, ..."},
+														"Category": {Type: llm.TypeString, Description: "produce, meat_fish, dairy_eggs, grains_bakery, spices_condiments, pantry, beverages or other"},
+													},
+													Required: []string{"Name", "Quantity", "Unit"},
+												},
+											},
+										},
+										Required: []string{"Name", "Portion", "Calories"},
+									},
+								},
+							},
+							Required: []string{"Name", "Items"},
+						},
+					},
+				},
+				Required: []string{"Day", "Meals"},
+			},
+		},
+	},
+	Required: []string{"Days"},
+}
+
+// extractJSON strips Markdown code fences and any text around the outermost
+// JSON value, which models sometimes add despite the JSON response type.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	start := strings.IndexAny(text, "{[")
+	end := strings.LastIndexAny(text, "}]")
+	if start < 0 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
+
+// roundIntegers rounds the numbers of value that the schema declares as
+// integers, since models sometimes answer "Calories": 350.5 which would
+// not decode into an int.
+func roundIntegers(value interface{}, schema *llm.Schema) interface{} {
+	if schema == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case float64:
+		if schema.Type == llm.TypeInteger {
+			return math.Round(v)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = roundIntegers(v[i], schema.Items)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = roundIntegers(v[key], schema.Properties[key])
+		}
+	}
+	return value
+}
+
+// parseMealPlan decodes, validates and repairs a generated meal plan.
+// Repairs are silent: fractional calories and prices are rounded, totals
+// are recomputed from the items, day numbers are renumbered and extra days
+// are dropped. Problems that cannot be repaired are returned as errors.
+// expectedDays is ignored when zero.
+func parseMealPlan(text string, expectedDays int) (*entity.MealPlan, []string) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(extractJSON(text)), &value); err != nil {
+		return nil, []string{"response is not valid meal plan JSON: " + err.Error()}
+	}
+	// Some answers are a bare list of days
+	if days, ok := value.([]interface{}); ok {
+		value = map[string]interface{}{"Days": days}
+	}
+
+	repaired, err := json.Marshal(roundIntegers(value, mealPlanSchema))
+	if err != nil {
+		return nil, []string{"response is not valid meal plan JSON: " + err.Error()}
+	}
+	var plan entity.MealPlan
+	if err := json.Unmarshal(repaired, &plan); err != nil {
+		return nil, []string{"response is not valid meal plan JSON: " + err.Error()}
+	}
+
+	if errs := validateMealPlan(&plan, expectedDays); len(errs) > 0 {
+		return nil, errs
+	}
+	return &plan, nil
+}
+
+// validateMealPlan checks and repairs a decoded meal plan in place, see
+// parseMealPlan.
+func validateMealPlan(plan *entity.MealPlan, expectedDays int) []string {
+	var errs []string
+	if len(plan.Days) == 0 {
+		return []string{"meal plan has no days"}
+	}
+	if expectedDays > 0 {
+		if len(plan.Days) < expectedDays {
+			errs = append(errs, fmt.Sprintf("meal plan has %d days, expected %d", len(plan.Days), expectedDays))
+		} else {
+			plan.Days = plan.Days[:expectedDays]
+		}
+	}
+
+	for d := range plan.Days {
+		day := &plan.Days[d]
+		day.Day = d + 1
+		day.TotalCalories = 0
+		if len(day.Meals) == 0 {
+			errs = append(errs, fmt.Sprintf("day %d has no meals", day.Day))
+		}
+
+		for m := range day.Meals {
+			meal := &day.Meals[m]
+			meal.Name = strings.TrimSpace(meal.Name)
+			if meal.Name == "" {
+				meal.Name = fmt.Sprintf("meal %d", m+1)
+			}
+
+			// Drop items without a name instead of failing the whole plan
+			items := meal.Items[:0]
+			for _, item := range meal.Items {
+				item.Name = strings.TrimSpace(item.Name)
+				if item.Name == "" {
+					continue
+				}
+				if item.Calories <= 0 {
+					errs = append(errs, fmt.Sprintf("day %d, %s: %q has no calories", day.Day, meal.Name, item.Name))
+				}
+				if item.Protein < 0 || item.Carbohydrates < 0 || item.Fat < 0 {
+					errs = append(errs, fmt.Sprintf("day %d, %s: %q has negative macros", day.Day, meal.Name, item.Name))
+				}
+				items = append(items, item)
+			}
+			meal.Items = items
+			if len(meal.Items) == 0 {
+				errs = append(errs, fmt.Sprintf("day %d, %s has no items", day.Day, meal.Name))
+			}
+
+			meal.TotalCalories = 0
+			for _, item := range meal.Items {
+				meal.TotalCalories += item.Calories
+			}
+			day.TotalCalories += meal.TotalCalories
+		}
+	}
+
+	return errs
+}
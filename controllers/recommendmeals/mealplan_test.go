@@ -0,0 +1,89 @@
+package recommendmeals
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{`{"Days":[]}`, `{"Days":[]}`},
+		{"```json\n{\"Days\":[]}\n```", `{"Days":[]}`},
+		{"Here is your plan: {\"Days\":[]} Enjoy!", `{"Days":[]}`},
+		{`[{"Day":1}]`, `[{"Day":1}]`},
+		{"no json", "no json"},
+	}
+	for _, tt := range tests {
+		if got := extractJSON(tt.text); got != tt.want {
+			t.Errorf("extractJSON(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseMealPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		days         int
+		wantDays     int
+		wantCalories []int
+		wantErr      string
+	}{
+		{
+			name:         "totals are recomputed",
+			text:         `{"Days":[{"Day":7,"TotalCalories":1,"Meals":[{"Name":"breakfast","TotalCalories":1,"Items":[{"Name":"Nasi","Portion":"1 cup","Calories":200},{"Name":"Telur","Portion":"1","Calories":80}]}]}]}`,
+			days:         1,
+			wantDays:     1,
+			wantCalories: []int{280},
+		},
+		{
+			name:         "fractional calories are rounded",
+			text:         `{"Days":[{"Day":1,"Meals":[{"Name":"lunch","Items":[{"Name":"Soto","Portion":"1 bowl","Calories":350.5,"Protein":20.5,"Price":12000.4}]}]}]}`,
+			wantDays:     1,
+			wantCalories: []int{351},
+		},
+		{
+			name:         "bare list of days in a code fence, extra days dropped",
+			text:         "```json\n[{\"Day\":1,\"Meals\":[{\"Name\":\"dinner\",\"Items\":[{\"Name\":\"Tempe\",\"Portion\":\"2 pieces\",\"Calories\":150}]}]},{\"Day\":2,\"Meals\":[{\"Name\":\"dinner\",\"Items\":[{\"Name\":\"Tahu\",\"Portion\":\"2 pieces\",\"Calories\":120}]}]}]\n```",
+			days:         1,
+			wantDays:     1,
+			wantCalories: []int{150},
+		},
+		{
+			name:     "unnamed items are dropped",
+			text:     `{"Days":[{"Meals":[{"Name":"","Items":[{"Name":" ","Calories":10},{"Name":"Apel","Portion":"1","Calories":95}]}]}]}`,
+			wantDays: 1, wantCalories: []int{95},
+		},
+		{name: "not JSON", text: "Sorry, I can't help with that", wantErr: "not valid meal plan JSON"},
+		{name: "wrong type", text: `{"Days":[{"Meals":"none"}]}`, wantErr: "not valid meal plan JSON"},
+		{name: "no days", text: `{"Days":[]}`, wantErr: "no days"},
+		{name: "too few days", text: `{"Days":[{"Meals":[{"Name":"x","Items":[{"Name":"Apel","Calories":95}]}]}]}`, days: 3, wantErr: "expected 3"},
+		{name: "no calories", text: `{"Days":[{"Meals":[{"Name":"x","Items":[{"Name":"Air","Calories":0}]}]}]}`, wantErr: "has no calories"},
+		{name: "negative macros", text: `{"Days":[{"Meals":[{"Name":"x","Items":[{"Name":"Apel","Calories":95,"Fat":-1}]}]}]}`, wantErr: "negative macros"},
+		{name: "empty meal", text: `{"Days":[{"Meals":[{"Name":"x","Items":[]}]}]}`, wantErr: "has no items"},
+	}
+	for _, tt := range tests {
+		plan, errs := parseMealPlan(tt.text, tt.days)
+		if tt.wantErr != "" {
+			if len(errs) == 0 || !strings.Contains(strings.Join(errs, "; "), tt.wantErr) {
+				t.Errorf("%s: errors %v, want %q", tt.name, errs, tt.wantErr)
+			}
+			continue
+		}
+		if len(errs) > 0 {
+			t.Errorf("%s: unexpected errors %v", tt.name, errs)
+			continue
+		}
+		if len(plan.Days) != tt.wantDays {
+			t.Errorf("%s: %d days, want %d", tt.name, len(plan.Days), tt.wantDays)
+			continue
+		}
+		for i, want := range tt.wantCalories {
+			if plan.Days[i].Day != i+1 || plan.Days[i].TotalCalories != want {
+				t.Errorf("%s: day %d is number %d with %d calories, want %d", tt.name, i, plan.Days[i].Day, plan.Days[i].TotalCalories, want)
+			}
+		}
+	}
+}
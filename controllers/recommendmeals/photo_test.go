@@ -0,0 +1,40 @@
+package recommendmeals
+
+import (
+	"reflect"
+	"testing"
+
+	"nutrishe/models"
+)
+
+func TestLoggedFoods(t *testing.T) {
+	tests := []struct {
+		name string
+		add  []models.LoggedFood
+		want []models.LoggedFood
+	}{
+		{"none", nil, []models.LoggedFood{}},
+		{
+			name: "servings are kept",
+			add:  []models.LoggedFood{{FoodID: "FD001", Servings: 2}, {FoodID: "FD002", Servings: 0.5}},
+			want: []models.LoggedFood{{FoodID: "FD001", Servings: 2}, {FoodID: "FD002", Servings: 0.5}},
+		},
+		{
+			name: "repeated foods are added up",
+			add:  []models.LoggedFood{{FoodID: "FD001", Servings: 1}, {FoodID: "FD002", Servings: 1}, {FoodID: "FD001", Servings: 1.333}},
+			want: []models.LoggedFood{{FoodID: "FD001", Servings: 2.33}, {FoodID: "FD002", Servings: 1}},
+		},
+	}
+	for _, tt := range tests {
+		foods := &loggedFoods{list: []models.LoggedFood{}}
+		for _, food := range tt.add {
+			foods.add(food.FoodID, food.Servings)
+		}
+		if !reflect.DeepEqual(foods.list, tt.want) {
+			t.Errorf("%s: foods = %v, want %v", tt.name, foods.list, tt.want)
+		}
+		if len(foods.ids()) != len(tt.want) {
+			t.Errorf("%s: ids = %v, want %d", tt.name, foods.ids(), len(tt.want))
+		}
+	}
+}
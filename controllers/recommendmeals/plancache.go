@@ -0,0 +1,101 @@
+package recommendmeals
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+// maxCachedPlans bounds the memory used by the plan cache.
+const maxCachedPlans = 1000
+
+// planCache keeps meal plans that passed the safety checks, so asking again
+// for the same plan with an unchanged profile is answered without a new
+// generation. Rejected answers are never cached, so retrying after a
+// rejection always generates a new plan.
+type planCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPlan
+}
+
+type cachedPlan struct {
+	plan     *entity.MealPlan
+	warnings []models.TagConflict
+	expires  time.Time
+}
+
+var plans = &planCache{entries: make(map[string]cachedPlan)}
+
+// planCacheTTL is MEAL_PLAN_CACHE_TTL, a duration such as "30m", defaulting
+// to one hour. Zero or an invalid value disables the cache.
+func planCacheTTL() time.Duration {
+	v, ok := os.LookupEnv("MEAL_PLAN_CACHE_TTL")
+	if !ok || v == "" {
+		return time.Hour
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		return 0
+	}
+	return ttl
+}
+
+// planKey identifies a validated request for a user's profile. The profile
+// holds the user, so plans are never shared between users.
+func planKey(input *mealPlanInput, profile *models.NutritionProfile) string {
+	data, _ := json.Marshal(struct {
+		Input   *mealPlanInput
+		Profile *models.NutritionProfile
+	}{input, profile})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *planCache) get(key string) (*entity.MealPlan, []models.TagConflict, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, nil, false
+	}
+	return entry.plan, entry.warnings, true
+}
+
+func (c *planCache) put(key string, plan *entity.MealPlan, warnings []models.TagConflict) {
+	ttl := planCacheTTL()
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxCachedPlans {
+		var oldest string
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= maxCachedPlans {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = cachedPlan{plan: plan, warnings: warnings, expires: now.Add(ttl)}
+}
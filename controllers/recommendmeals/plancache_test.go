@@ -0,0 +1,58 @@
+package recommendmeals
+
+import (
+	"fmt"
+	"testing"
+
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+func TestPlanKey(t *testing.T) {
+	input := &mealPlanInput{Days: 1, Calories: 1800, Cuisine: "Indonesian"}
+	profile := &models.NutritionProfile{UserID: "US001", Allergies: []string{"peanut"}}
+
+	tests := []struct {
+		name    string
+		input   *mealPlanInput
+		profile *models.NutritionProfile
+		same    bool
+	}{
+		{"same request", &mealPlanInput{Days: 1, Calories: 1800, Cuisine: "Indonesian"}, &models.NutritionProfile{UserID: "US001", Allergies: []string{"peanut"}}, true},
+		{"other days", &mealPlanInput{Days: 2, Calories: 1800, Cuisine: "Indonesian"}, profile, false},
+		{"other user", input, &models.NutritionProfile{UserID: "US002", Allergies: []string{"peanut"}}, false},
+		{"changed allergies", input, &models.NutritionProfile{UserID: "US001", Allergies: []string{"peanut", "milk"}}, false},
+	}
+	for _, tt := range tests {
+		if got := planKey(tt.input, tt.profile) == planKey(input, profile); got != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
+
+func TestPlanCache(t *testing.T) {
+	t.Setenv("MEAL_PLAN_CACHE_TTL", "1h")
+	cache := &planCache{entries: make(map[string]cachedPlan)}
+	plan := &entity.MealPlan{Days: []entity.MealPlanDay{{Day: 1}}}
+
+	if _, _, ok := cache.get("a"); ok {
+		t.Fatal("empty cache returned a plan")
+	}
+	cache.put("a", plan, nil)
+	if got, _, ok := cache.get("a"); !ok || got != plan {
+		t.Fatalf("get(a) = %v, %v, want the stored plan", got, ok)
+	}
+
+	for i := 0; i < maxCachedPlans+10; i++ {
+		cache.put(fmt.Sprint(i), plan, nil)
+	}
+	if len(cache.entries) > maxCachedPlans {
+		t.Errorf("cache holds %d plans, want at most %d", len(cache.entries), maxCachedPlans)
+	}
+
+	t.Setenv("MEAL_PLAN_CACHE_TTL", "0")
+	cache.put("disabled", plan, nil)
+	if _, _, ok := cache.get("disabled"); ok {
+		t.Error("plan cached with the cache disabled")
+	}
+}
@@ -0,0 +1,76 @@
+package recommendmeals
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nutrishe/auth"
+	"nutrishe/llm"
+	"nutrishe/models"
+)
+
+// reserveQuota reserves one AI request of the user for endpoint. It writes
+// a 429 response and returns false when the user has no requests left. The
+// reservation must be completed with recordUsage or releaseQuota.
+func reserveQuota(w http.ResponseWriter, userID, endpoint string) (int64, bool) {
+	now := time.Now()
+	usageID, quota, err := models.ReserveAIUsage(models.GetDB(), userID, endpoint, now)
+	if err == nil {
+		return usageID, true
+	}
+	if err != models.ErrAIQuotaExceeded {
+		http.Error(w, "Failed to check AI quota: "+err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+
+	resetsAt := quota.DailyResetsAt
+	if quota.RemainingMonth <= 0 {
+		resetsAt = quota.MonthlyResetsAt
+	}
+	if t, err := time.Parse(time.RFC3339, resetsAt); err == nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(t.Sub(now).Seconds())))
+	}
+	respondJSON(w, http.StatusTooManyRequests, map[string]interface{}{
+		"message": "AI request quota exceeded",
+		"quota":   quota,
+	})
+	return 0, false
+}
+
+// recordUsage stores the cost of a reserved generation. Failing to record
+// it must not fail the request that already succeeded.
+func recordUsage(usageID int64, resp *llm.Response) {
+	err := models.UpdateAIUsage(models.GetDB(), usageID, resp.PromptTokens, resp.OutputTokens)
+	if err != nil {
+		log.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+// recordCachedUsage stores a request answered from the cache, which does
+// not use up the quota.
+func recordCachedUsage(userID, endpoint string) {
+	if err := models.RecordAIUsage(models.GetDB(), userID, endpoint, 0, 0, true); err != nil {
+		log.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+// releaseQuota gives back a reserved request when the provider generated
+// nothing, e.g. because it was unreachable.
+func releaseQuota(usageID int64) {
+	if err := models.ReleaseAIUsage(models.GetDB(), usageID); err != nil {
+		log.Printf("Failed to release AI usage: %v", err)
+	}
+}
+
+// GetAIQuota shows the authenticated user's AI usage and remaining requests.
+func GetAIQuota(w http.ResponseWriter, r *http.Request) {
+	quota, err := models.GetAIQuota(models.GetDB(), auth.CurrentUserID(r), time.Now())
+	if err != nil {
+		http.Error(w, "Failed to retrieve AI quota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, quota)
+}
@@ -1,15 +1,17 @@
 package recommendmeals
 
 import (
-	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"os"
-
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
+	"strings"
+	"time"
 
+	"nutrishe/auth"
 	"nutrishe/entity"
+	"nutrishe/llm"
+	"nutrishe/locale"
+	"nutrishe/models"
 
 	"log"
 )
@@ -22,44 +24,152 @@ func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-func RecommendMeals(w http.ResponseWriter, r *http.Request) {
-	log.Println("rekomen ai")
+// generation is a prepared meal plan request and what is needed to check
+// its answer.
+type generation struct {
+	// key identifies the request in the plan cache.
+	key       string
+	request   llm.Request
+	input     *mealPlanInput
+	prefs     *models.UserPreferences
+	catalogue []models.CatalogueFood
+}
 
+// prepareGeneration builds the prompt for the authenticated user. On
+// failure it writes the error response and returns nil.
+func prepareGeneration(w http.ResponseWriter, r *http.Request) *generation {
 	var data entity.AIPrompt
 	err := json.NewDecoder(r.Body).Decode(&data)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil
 	}
 
-	// prompt: Generate a meal plan for ? day, ? calories each day, with calories for each meal. Specific to ? dishes.
+	// The prompt is built from the authenticated user's stored profile
+	userID := auth.CurrentUserID(r)
+	db := models.GetDB()
+	profile, err := models.GetNutritionProfile(db, userID, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to retrieve nutrition profile: "+err.Error(), http.StatusInternalServerError)
+		return nil
+	}
 
-	//making prompt
-	prompt := "Generate a meal plan for  " + data.Days + " days, " + data.Calories +
-		" calories each day, with calories for each meal. Specific to " + data.Cuisine + " cuisines."
+	input, errs := parsePromptInput(data, profile)
+	if len(errs) > 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid meal plan request",
+			"errors":  errs,
+		})
+		return nil
+	}
+	prompt := buildPrompt(input, profile)
 
-	log.Print("prompt: ", prompt)
+	catalogue, err := models.GetFoodCatalogue(db)
+	if err != nil {
+		http.Error(w, "Failed to retrieve food catalogue: "+err.Error(), http.StatusInternalServerError)
+		return nil
+	}
+	prompt += catalogueHint(catalogue, input.Budget > 0)
 
-	ctx := context.Background()
-	client, _ := genai.NewClient(ctx, option.WithAPIKey(os.Getenv("Genai_API_KEY")))
+	// The prompt holds health data, so only its shape is logged
+	log.Printf("Generating a %d day meal plan for user %s", input.Days, userID)
 
-	defer client.Close()
+	return &generation{
+		key:       planKey(input, profile),
+		request:   llm.Request{System: mealPlanSystem, Prompt: prompt, Schema: mealPlanSchema},
+		input:     input,
+		prefs:     &models.UserPreferences{UserID: userID, Allergies: profile.Allergies, Diets: profile.Diets},
+		catalogue: catalogue,
+	}
+}
 
-	model := client.GenerativeModel("gemini-1.5-flash")
-	resp, _ := model.GenerateContent(ctx, genai.Text(prompt))
+// finish parses the generated text into a plan and links its items to the
+// catalogue. Plans with a day below the safe calorie minimum or above the
+// daily budget, or with dishes that contain or look like they contain the
+// user's allergens, are rejected; diet conflicts are returned as warnings.
+// Accepted plans are cached.
+func (g *generation) finish(text string) (*entity.MealPlan, []models.TagConflict, []string) {
+	plan, errs := parseMealPlan(text, g.input.Days)
+	if errs != nil {
+		return nil, nil, errs
+	}
 
-	var aiResponse genai.Part
-	if resp != nil {
-		candidates := resp.Candidates
+	warnings := []models.TagConflict{}
+	for _, conflict := range groundMealPlan(plan, g.catalogue, g.prefs) {
+		if conflict.Kind == models.PreferenceAllergy {
+			errs = append(errs, fmt.Sprintf("food %s: %s", conflict.FoodID, conflict.Message))
+		} else {
+			warnings = append(warnings, conflict)
+		}
+	}
 
-		for _, candidate := range candidates {
-			content := candidate.Content
-			if content != nil {
-				aiResponse = content.Parts[0]
-				log.Print("AI response:  ", aiResponse)
+	for _, day := range plan.Days {
+		if day.TotalCalories < minSafeCalories {
+			errs = append(errs, fmt.Sprintf("day %d has %d calories, below the safe minimum of %d", day.Day, day.TotalCalories, minSafeCalories))
+		}
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				for _, conflict := range models.TextConflicts(item.Name, g.prefs) {
+					errs = append(errs, fmt.Sprintf("day %d, %s: %q %s", day.Day, meal.Name, item.Name, strings.ToLower(conflict.Message)))
+				}
 			}
 		}
 	}
 
-	respondJSON(w, http.StatusOK, aiResponse)
+	estimateCosts(plan, g.catalogue)
+	errs = append(errs, budgetErrors(plan, g.input.Budget)...)
+
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	plans.put(g.key, plan, warnings)
+	return plan, warnings, nil
+}
+
+func RecommendMeals(w http.ResponseWriter, r *http.Request) {
+	log.Println("rekomen ai")
+
+	gen := prepareGeneration(w, r)
+	if gen == nil {
+		return
+	}
+
+	userID := gen.prefs.UserID
+	if plan, warnings, ok := plans.get(gen.key); ok {
+		recordCachedUsage(userID, "recommend_meals")
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"plan":       plan,
+			"warnings":   warnings,
+			"disclaimer": locale.Message(locale.FromRequest(r), "medical_disclaimer"),
+		})
+		return
+	}
+
+	usageID, ok := reserveQuota(w, userID, "recommend_meals")
+	if !ok {
+		return
+	}
+	resp, err := llm.Get().Generate(r.Context(), gen.request)
+	if err != nil {
+		log.Printf("Failed to generate meal plan: %v", err)
+		releaseQuota(usageID)
+		respondJSON(w, http.StatusBadGateway, map[string]string{"message": "Failed to generate meal plan", "error": err.Error()})
+		return
+	}
+	recordUsage(usageID, resp)
+
+	plan, warnings, errs := gen.finish(resp.Text)
+	if errs != nil {
+		respondJSON(w, http.StatusBadGateway, map[string]interface{}{
+			"message": "Generated meal plan failed validation",
+			"errors":  errs,
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"plan":       plan,
+		"warnings":   warnings,
+		"disclaimer": locale.Message(locale.FromRequest(r), "medical_disclaimer"),
+	})
 }
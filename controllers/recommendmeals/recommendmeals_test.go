@@ -0,0 +1,166 @@
+package recommendmeals
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nutrishe/auth"
+	"nutrishe/llm"
+	"nutrishe/models"
+	"nutrishe/models/dbtest"
+)
+
+const reserveStatement = "SELECT UserID FROM users WHERE UserID = ? FOR UPDATE"
+
+// usedRequests answers the AI usage queries as if the user had made n
+// requests today and this month; everything else finds nothing.
+func usedRequests(n int) dbtest.Responder {
+	return func(query string, args []driver.Value) ([][]driver.Value, error) {
+		if strings.Contains(query, "FROM ai_usage") {
+			return [][]driver.Value{{int64(n), int64(0), int64(0), int64(0)}}, nil
+		}
+		return nil, nil
+	}
+}
+
+// setup points the handlers at provider and a database answered by
+// respond, with an empty plan cache.
+func setup(t *testing.T, provider llm.Provider, respond dbtest.Responder) *dbtest.DB {
+	prevProvider, prevPlans := llm.Get(), plans
+	t.Cleanup(func() {
+		llm.SetProvider(prevProvider)
+		models.SetDB(nil)
+		plans = prevPlans
+	})
+
+	llm.SetProvider(provider)
+	plans = &planCache{entries: make(map[string]cachedPlan)}
+	sqlDB, db := dbtest.Open(respond)
+	models.SetDB(sqlDB)
+	return db
+}
+
+func planRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"Days":"1","Calories":"1800"}`))
+	return auth.WithUserID(r, "US001")
+}
+
+func TestRecommendMeals(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   *llm.Fake
+		used       int
+		wantStatus int
+		wantBody   string
+		// Whether the reserved request was completed or given back
+		wantRecorded bool
+		wantReleased bool
+	}{
+		{"generated", &llm.Fake{}, 0, http.StatusOK, `"plan":{"Days":[`, true, false},
+		{"provider fails", &llm.Fake{Err: errors.New("provider unreachable")}, 0, http.StatusBadGateway, "provider unreachable", false, true},
+		{"invalid plan", &llm.Fake{Text: "no plan today"}, 0, http.StatusBadGateway, "failed validation", true, false},
+		{"unsafe plan", &llm.Fake{Text: `{"Days":[{"Meals":[{"Name":"lunch","Items":[{"Name":"Apel","Portion":"1","Calories":95}]}]}]}`}, 0, http.StatusBadGateway, "below the safe minimum", true, false},
+		{"quota used up", &llm.Fake{}, 20, http.StatusTooManyRequests, "quota exceeded", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setup(t, tt.provider, usedRequests(tt.used))
+
+			w := httptest.NewRecorder()
+			RecommendMeals(w, planRequest("/recommend_meals"))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %q", w.Body, tt.wantBody)
+			}
+			if got := db.Ran("UPDATE ai_usage"); got != tt.wantRecorded {
+				t.Errorf("usage recorded = %v, want %v", got, tt.wantRecorded)
+			}
+			if got := db.Ran("DELETE FROM ai_usage"); got != tt.wantReleased {
+				t.Errorf("usage released = %v, want %v", got, tt.wantReleased)
+			}
+		})
+	}
+}
+
+func TestRecommendMealsCached(t *testing.T) {
+	t.Setenv("MEAL_PLAN_CACHE_TTL", "1h")
+	setup(t, &llm.Fake{}, usedRequests(0))
+
+	w := httptest.NewRecorder()
+	RecommendMeals(w, planRequest("/recommend_meals"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	// The same request is answered from the cache, without the provider
+	// or the quota
+	llm.SetProvider(&llm.Fake{Err: errors.New("provider unreachable")})
+	sqlDB, db := dbtest.Open(usedRequests(20))
+	models.SetDB(sqlDB)
+	w = httptest.NewRecorder()
+	RecommendMeals(w, planRequest("/recommend_meals"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cached status = %d: %s", w.Code, w.Body)
+	}
+	if db.Ran(reserveStatement) {
+		t.Error("cached plan reserved a request")
+	}
+	if !db.Ran("INSERT INTO ai_usage") {
+		t.Error("cached plan was not recorded")
+	}
+}
+
+func TestRecommendMealsStream(t *testing.T) {
+	unsafe := `{"Days":[{"Meals":[{"Name":"lunch","Items":[{"Name":"Apel","Portion":"1","Calories":95}]}]}]}`
+	tests := []struct {
+		name         string
+		provider     *llm.Fake
+		used         int
+		wantStatus   int
+		wantEvents   []string
+		wantAbsent   []string
+		wantRecorded bool
+		wantReleased bool
+	}{
+		{"generated", &llm.Fake{}, 0, http.StatusOK, []string{"event: chunk", `"text":"{\"Days\"`, "event: plan"}, []string{"event: rejected", "event: error"}, true, false},
+		{"provider fails", &llm.Fake{Err: errors.New("provider unreachable")}, 0, http.StatusOK, []string{"event: error", "provider unreachable"}, []string{"event: chunk", "event: plan"}, false, true},
+		// Text is shown as it is written, then the client is told to
+		// discard it
+		{"invalid plan", &llm.Fake{Text: "no plan today"}, 0, http.StatusOK, []string{"event: chunk", "no plan today", "event: rejected", "failed validation"}, []string{"event: plan"}, true, false},
+		{"unsafe plan", &llm.Fake{Text: unsafe}, 0, http.StatusOK, []string{"event: chunk", "event: rejected", "below the safe minimum"}, []string{"event: plan"}, true, false},
+		{"quota used up", &llm.Fake{}, 20, http.StatusTooManyRequests, []string{"quota exceeded"}, []string{"event: chunk"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setup(t, tt.provider, usedRequests(tt.used))
+
+			w := httptest.NewRecorder()
+			RecommendMealsStream(w, planRequest("/recommend_meals/stream"))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			for _, event := range tt.wantEvents {
+				if !strings.Contains(w.Body.String(), event) {
+					t.Errorf("body = %s, want it to contain %q", w.Body, event)
+				}
+			}
+			for _, event := range tt.wantAbsent {
+				if strings.Contains(w.Body.String(), event) {
+					t.Errorf("body = %s, want no %q", w.Body, event)
+				}
+			}
+			if got := db.Ran("UPDATE ai_usage"); got != tt.wantRecorded {
+				t.Errorf("usage recorded = %v, want %v", got, tt.wantRecorded)
+			}
+			if got := db.Ran("DELETE FROM ai_usage"); got != tt.wantReleased {
+				t.Errorf("usage released = %v, want %v", got, tt.wantReleased)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package recommendmeals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nutrishe/auth"
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+type AcceptMealPlanRequest struct {
+	Plan      entity.MealPlan `json:"plan"`
+	StartDate string          `json:"start_date"`
+}
+
+type MarkMealEatenRequest struct {
+	ScheduleID int  `json:"schedule_id"`
+	Force      bool `json:"force"`
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// AcceptMealPlan stores a generated meal plan as planned meals, day 1 on
+// start_date, attached to the diet plan covering those dates.
+func AcceptMealPlan(w http.ResponseWriter, r *http.Request) {
+	var req AcceptMealPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	start, err := parseDate(req.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start_date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	errs := validateMealPlan(&req.Plan, 0)
+	for _, day := range req.Plan.Days {
+		if day.TotalCalories < minSafeCalories {
+			errs = append(errs, fmt.Sprintf("day %d has %d calories, below the safe minimum of %d", day.Day, day.TotalCalories, minSafeCalories))
+		}
+	}
+	if len(errs) > 0 {
+		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message": "Meal plan failed validation",
+			"errors":  errs,
+		})
+		return
+	}
+
+	userID := auth.CurrentUserID(r)
+	db := models.GetDB()
+	plan, err := models.GetCurrentDietPlan(db, userID, start)
+	if err != nil {
+		http.Error(w, "Failed to retrieve diet plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	end := start.AddDate(0, 0, len(req.Plan.Days)-1)
+	if plan == nil || end.After(plan.EndDate) {
+		respondJSON(w, http.StatusConflict, map[string]string{"message": "No diet plan covers every day of the meal plan"})
+		return
+	}
+
+	if err := models.SaveMealPlanSchedule(db, userID, plan.PlanID, start, &req.Plan); err != nil {
+		http.Error(w, "Failed to save meal plan: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	meals, err := models.GetScheduledMeals(db, userID, start, end)
+	if err != nil {
+		http.Error(w, "Failed to retrieve planned meals: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"message": "Meal plan saved",
+		"plan_id": plan.PlanID,
+		"meals":   meals,
+	})
+}
+
+// GetPlannedMeals lists planned meals for ?date, or for ?from to ?to.
+func GetPlannedMeals(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if date := query.Get("date"); date != "" {
+		from, to = date, date
+	}
+
+	start, err := parseDate(from)
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	end := start
+	if to != "" {
+		if end, err = parseDate(to); err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if end.Before(start) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	meals, err := models.GetScheduledMeals(models.GetDB(), auth.CurrentUserID(r), start, end)
+	if err != nil {
+		http.Error(w, "Failed to retrieve planned meals: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, meals)
+}
+
+// MarkMealEaten logs a planned meal in the food diary and marks it eaten.
+func MarkMealEaten(w http.ResponseWriter, r *http.Request) {
+	var req MarkMealEatenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.CurrentUserID(r)
+	db := models.GetDB()
+	meal, err := models.GetScheduledMeal(db, req.ScheduleID)
+	if err == models.ErrScheduledMealNotFound || (err == nil && meal.UserID != userID) {
+		http.Error(w, "Planned meal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve planned meal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if meal.Eaten {
+		respondJSON(w, http.StatusConflict, map[string]string{"message": "Planned meal is already marked eaten"})
+		return
+	}
+	if meal.FoodID == nil {
+		respondJSON(w, http.StatusBadRequest, map[string]string{"message": "Planned meal is not linked to a catalogue food; log it manually"})
+		return
+	}
+
+	conflicts, err := models.CheckFoodConflicts(db, userID, []string{*meal.FoodID})
+	if err != nil {
+		http.Error(w, "Failed to check dietary preferences: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if models.HasAllergyConflict(conflicts) && !req.Force {
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"message":   "Food conflicts with your allergies, resend with force to log it anyway",
+			"conflicts": conflicts,
+		})
+		return
+	}
+
+	if err := models.MarkScheduledMealEaten(db, meal); err != nil {
+		if err == models.ErrScheduledMealEaten {
+			respondJSON(w, http.StatusConflict, map[string]string{"message": "Planned meal is already marked eaten"})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidFood) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to log planned meal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Planned meal logged",
+		"meal":     meal,
+		"warnings": conflicts,
+	})
+}
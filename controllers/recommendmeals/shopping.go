@@ -0,0 +1,135 @@
+package recommendmeals
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"nutrishe/auth"
+	"nutrishe/entity"
+	"nutrishe/models"
+)
+
+type ShoppingListRequest struct {
+	// Plan is an AI-generated plan to shop for. When empty, the planned
+	// meals not yet eaten between From and To are used.
+	Plan *entity.MealPlan `json:"plan"`
+	From string           `json:"from"`
+	To   string           `json:"to"`
+	// Pantry adds items to the stored pantry for this list only.
+	Pantry []models.PantryItem `json:"pantry"`
+	// Format is "json" (the default) or "text".
+	Format string `json:"format"`
+}
+
+type PantryRequest struct {
+	Items []models.PantryItem `json:"items"`
+}
+
+// ShoppingList aggregates the ingredients of a plan into a grocery list
+// grouped by aisle, without what the user has in the pantry.
+func ShoppingList(w http.ResponseWriter, r *http.Request) {
+	var req ShoppingListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		req.Format = format
+	}
+	if req.Format != "" && req.Format != "json" && req.Format != "text" {
+		http.Error(w, "format must be json or text", http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.CurrentUserID(r)
+	db := models.GetDB()
+
+	var ingredients []entity.Ingredient
+	if req.Plan != nil && len(req.Plan.Days) > 0 {
+		ingredients = models.PlanIngredients(req.Plan)
+	} else {
+		from, err := parseDate(req.From)
+		if err != nil {
+			http.Error(w, "Invalid from, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		// A week of planned meals unless told otherwise
+		to := from.AddDate(0, 0, 6)
+		if req.To != "" {
+			if to, err = parseDate(req.To); err != nil {
+				http.Error(w, "Invalid to, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+		if to.Before(from) {
+			http.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		ingredients, err = models.GetScheduledIngredients(db, userID, from, to)
+		if err != nil {
+			http.Error(w, "Failed to retrieve planned meals: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	pantry, err := models.GetPantry(db, userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve pantry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pantry = append(pantry, req.Pantry...)
+
+	list := models.BuildShoppingList(ingredients, pantry)
+	if req.Format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="shopping-list.txt"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(models.ShoppingListText(list)))
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"aisles": list})
+}
+
+// GetPantry lists what the authenticated user has marked as in the pantry.
+func GetPantry(w http.ResponseWriter, r *http.Request) {
+	items, err := models.GetPantry(models.GetDB(), auth.CurrentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to retrieve pantry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
+
+// UpdatePantry replaces the authenticated user's pantry.
+func UpdatePantry(w http.ResponseWriter, r *http.Request) {
+	var req PantryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	items := []models.PantryItem{}
+	for _, item := range req.Items {
+		item.Name = strings.TrimSpace(item.Name)
+		item.Unit = strings.ToLower(strings.TrimSpace(item.Unit))
+		if item.Name == "" {
+			continue
+		}
+		if item.Quantity < 0 {
+			http.Error(w, "quantity must not be negative", http.StatusBadRequest)
+			return
+		}
+		items = append(items, item)
+	}
+
+	if err := models.SavePantry(models.GetDB(), auth.CurrentUserID(r), items); err != nil {
+		http.Error(w, "Failed to save pantry: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, items)
+}
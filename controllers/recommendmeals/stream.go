@@ -0,0 +1,117 @@
+package recommendmeals
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"nutrishe/llm"
+	"nutrishe/locale"
+)
+
+// sseWriter writes Server-Sent Events and flushes each one to the client.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// RecommendMealsStream is the streaming variant of RecommendMeals. The
+// generated text is relayed as "chunk" events while the model writes it,
+// and the stream ends with one of:
+//
+//   - "plan", with the parsed plan and warnings, once the text passed the
+//     same safety checks as RecommendMeals;
+//   - "rejected", when the finished plan failed those checks, e.g. because
+//     it contains the user's allergens or is over budget;
+//   - "error", when generation failed.
+//
+// The checks need the whole plan, so chunks are shown before they are
+// checked. After "rejected" or "error" the client must discard the text it
+// showed. Generation stops when the client disconnects.
+func RecommendMealsStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	gen := prepareGeneration(w, r)
+	if gen == nil {
+		return
+	}
+
+	userID := gen.prefs.UserID
+	cached, cachedWarnings, isCached := plans.get(gen.key)
+	var usageID int64
+	if !isCached {
+		if usageID, ok = reserveQuota(w, userID, "recommend_meals/stream"); !ok {
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	sse := &sseWriter{w: w, flusher: flusher}
+
+	if isCached {
+		recordCachedUsage(userID, "recommend_meals/stream")
+		sse.send("plan", map[string]interface{}{
+			"plan":       cached,
+			"warnings":   cachedWarnings,
+			"disclaimer": locale.Message(locale.FromRequest(r), "medical_disclaimer"),
+		})
+		return
+	}
+
+	// r.Context is cancelled when the client goes away, which stops the
+	// provider's stream
+	resp, err := llm.Get().Stream(r.Context(), gen.request, func(chunk string) error {
+		return sse.send("chunk", map[string]string{"text": chunk})
+	})
+	if err != nil {
+		// What was generated before the stream stopped is paid for
+		if resp != nil {
+			recordUsage(usageID, resp)
+		} else {
+			releaseQuota(usageID)
+		}
+		if r.Context().Err() != nil {
+			log.Print("Client disconnected during meal plan stream")
+			return
+		}
+		log.Printf("Failed to stream meal plan: %v", err)
+		sse.send("error", map[string]string{"message": "Failed to generate meal plan", "error": err.Error()})
+		return
+	}
+	recordUsage(usageID, resp)
+
+	plan, warnings, errs := gen.finish(resp.Text)
+	if errs != nil {
+		sse.send("rejected", map[string]interface{}{
+			"message": "Generated meal plan failed validation",
+			"errors":  errs,
+		})
+		return
+	}
+
+	sse.send("plan", map[string]interface{}{
+		"plan":       plan,
+		"warnings":   warnings,
+		"disclaimer": locale.Message(locale.FromRequest(r), "medical_disclaimer"),
+	})
+}
@@ -1,7 +1,12 @@
 package entity
 
+// AIPrompt holds the client's choices for a meal plan. The rest of the
+// prompt comes from the authenticated user's stored profile; an empty
+// Calories uses the user's calorie target.
 type AIPrompt struct {
 	Days     string `json:"Days"`
 	Calories string `json:"Calories"`
 	Cuisine  string `json:"Cuisine"`
+	// Budget is an optional daily spending cap in rupiah.
+	Budget string `json:"Budget"`
 }
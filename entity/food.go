@@ -1,8 +1,22 @@
 package entity
 
 type Food struct {
-	FoodID   string `json:"FoodID"`
-	Name     string `json:"Name"`
-	Serving  int    `json:"Serving"`
-	Calories int    `json:"Calories"`
+	FoodID   string   `json:"FoodID"`
+	Name     string   `json:"Name"`
+	Serving  int      `json:"Serving"`
+	Calories int      `json:"Calories"`
+	Tags     []string `json:"Tags"`
+	// Names holds localized names keyed by language code ("id", "en").
+	Names     map[string]string `json:"Names"`
+	Nutrients *FoodNutrients    `json:"Nutrients"`
+	// PricePerServing is the typical price of one serving in rupiah.
+	PricePerServing *int `json:"PricePerServing"`
+}
+
+// FoodNutrients holds the optional nutrients used for food scoring.
+type FoodNutrients struct {
+	Sugar        *float64 `json:"Sugar"`
+	SaturatedFat *float64 `json:"SaturatedFat"`
+	Sodium       *int     `json:"Sodium"`
+	Iron         *float64 `json:"Iron"`
 }
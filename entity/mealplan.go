@@ -0,0 +1,57 @@
+package entity
+
+// MealPlan is a multi-day meal plan as generated by the AI
+type MealPlan struct {
+	Days []MealPlanDay `json:"Days"`
+}
+
+type MealPlanDay struct {
+	Day           int    `json:"Day"`
+	TotalCalories int    `json:"TotalCalories"`
+	Meals         []Meal `json:"Meals"`
+	// EstimatedCost is the day's food cost in rupiah.
+	EstimatedCost int `json:"EstimatedCost,omitempty"`
+	// CostIncomplete is set when some items have no price, listed in
+	// UnpricedItems, so the day costs more than EstimatedCost and may be
+	// over budget.
+	CostIncomplete bool     `json:"CostIncomplete,omitempty"`
+	UnpricedItems  []string `json:"UnpricedItems,omitempty"`
+}
+
+type Meal struct {
+	Name          string     `json:"Name"`
+	TotalCalories int        `json:"TotalCalories"`
+	Items         []MealItem `json:"Items"`
+}
+
+type MealItem struct {
+	Name          string  `json:"Name"`
+	Portion       string  `json:"Portion"`
+	Calories      int     `json:"Calories"`
+	Protein       float64 `json:"Protein"`
+	Carbohydrates float64 `json:"Carbohydrates"`
+	Fat           float64 `json:"Fat"`
+	// Price is the estimated cost of the portion in rupiah. It is replaced
+	// by the catalogue price, scaled to the portion, when the item matches
+	// a food with a price.
+	Price int `json:"Price,omitempty"`
+	// Ingredients needed to cook the item, used for the shopping list.
+	Ingredients []Ingredient `json:"Ingredients,omitempty"`
+
+	// Filled in by matching the item against the food catalogue. Match is
+	// "matched", "substituted" (GeneratedName holds the AI's dish) or
+	// "unknown" (FoodID is empty and the item cannot be logged).
+	FoodID        string  `json:"FoodID,omitempty"`
+	Match         string  `json:"Match,omitempty"`
+	MatchScore    float64 `json:"MatchScore,omitempty"`
+	GeneratedName string  `json:"GeneratedName,omitempty"`
+}
+
+// Ingredient is an amount of something to buy. Category is the aisle, see
+// models.CategorizeIngredient.
+type Ingredient struct {
+	Name     string  `json:"Name"`
+	Quantity float64 `json:"Quantity"`
+	Unit     string  `json:"Unit"`
+	Category string  `json:"Category,omitempty"`
+}
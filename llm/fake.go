@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Fake is a deterministic provider for tests and offline development. It
+// returns Text when set; otherwise JSON requests with images get a fixed list
+// of recognised foods, other JSON requests get a small meal plan with as
+// many days as the prompt asks for, and text requests get an echo.
+type Fake struct {
+	Text string
+	Err  error
+}
+
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+var daysPattern = regexp.MustCompile(`(\d+)\s+days?`)
+
+func (f *Fake) Generate(ctx context.Context, req Request) (*Response, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	text := f.Text
+	if text == "" {
+		switch {
+		case req.Schema != nil && len(req.Images) > 0:
+			text = fakePhotoFoods
+		case req.Schema != nil:
+			text = fakeMealPlan(req.Prompt)
+		default:
+			text = "fake response: " + req.Prompt
+		}
+	}
+
+	return &Response{
+		Text:         text,
+		PromptTokens: len(strings.Fields(req.System + " " + req.Prompt)),
+		OutputTokens: len(strings.Fields(text)),
+	}, nil
+}
+
+const fakePhotoFoods = `{"Foods":[` +
+	`{"Name":"Nasi Putih","Portion":"1 plate","Servings":1,"Calories":204,"Confidence":0.9},` +
+	`{"Name":"Ayam Goreng","Portion":"1 piece","Servings":1,"Calories":260,"Confidence":0.8},` +
+	`{"Name":"Sambal","Portion":"1 tablespoon","Servings":1,"Calories":20,"Confidence":0.5}]}`
+
+// fakeChunkSize is how many bytes of text Stream sends at a time.
+const fakeChunkSize = 64
+
+func (f *Fake) Stream(ctx context.Context, req Request, fn ChunkFunc) (*Response, error) {
+	resp, err := f.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := 0
+	for sent < len(resp.Text) {
+		if err := ctx.Err(); err != nil {
+			return partial(resp, resp.Text[:sent]), err
+		}
+		n := fakeChunkSize
+		if n > len(resp.Text)-sent {
+			n = len(resp.Text) - sent
+		}
+		if err := fn(resp.Text[sent : sent+n]); err != nil {
+			return partial(resp, resp.Text[:sent+n]), err
+		}
+		sent += n
+	}
+	return resp, nil
+}
+
+func fakeMealPlan(prompt string) string {
+	days := 1
+	if m := daysPattern.FindStringSubmatch(prompt); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 && n <= 31 {
+			days = n
+		}
+	}
+
+	type item struct {
+		Name          string
+		Portion       string
+		Calories      int
+		Protein       float64
+		Carbohydrates float64
+		Fat           float64
+	}
+	type meal struct {
+		Name  string
+		Items []item
+	}
+	meals := []meal{
+		{"breakfast", []item{{"Oatmeal", "1 bowl", 300, 10, 54, 5}, {"Banana", "1 piece", 105, 1.3, 27, 0.4}}},
+		{"lunch", []item{{"Nasi Putih", "1 plate", 204, 4.2, 44, 0.4}, {"Ayam Bakar", "1 piece", 250, 27, 0, 15}}},
+		{"dinner", []item{{"Sayur Asem", "1 bowl", 80, 2, 15, 1}, {"Tempe Goreng", "2 pieces", 180, 11, 7, 12}}},
+		{"snack", []item{{"Apel", "1 piece", 95, 0.5, 25, 0.3}}},
+	}
+
+	plan := map[string]interface{}{}
+	var list []map[string]interface{}
+	for d := 1; d <= days; d++ {
+		list = append(list, map[string]interface{}{"Day": d, "Meals": meals})
+	}
+	plan["Days"] = list
+
+	data, _ := json.Marshal(plan)
+	return string(data)
+}
@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// Gemini generates text with Google's Gemini models.
+type Gemini struct {
+	client *genai.Client
+	model  string
+}
+
+// NewGemini creates the client once; it is safe for concurrent use.
+func NewGemini(ctx context.Context, apiKey, model string) (*Gemini, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	return &Gemini{client: client, model: model}, nil
+}
+
+func (g *Gemini) Name() string {
+	return "gemini"
+}
+
+// generativeModel returns a model configured for req. GenerativeModel is
+// cheap to create and holds per-request settings, so one is made per call.
+func (g *Gemini) generativeModel(req Request) *genai.GenerativeModel {
+	model := g.client.GenerativeModel(g.model)
+	if req.System != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(req.System)}}
+	}
+	if req.Schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = toGenaiSchema(req.Schema)
+	}
+	return model
+}
+
+// chatSession starts a chat holding the request's history.
+func (g *Gemini) chatSession(req Request) *genai.ChatSession {
+	cs := g.generativeModel(req).StartChat()
+	for _, msg := range req.History {
+		cs.History = append(cs.History, &genai.Content{Role: msg.Role, Parts: []genai.Part{genai.Text(msg.Text)}})
+	}
+	return cs
+}
+
+// promptParts is the prompt followed by its images.
+func promptParts(req Request) []genai.Part {
+	parts := []genai.Part{genai.Text(req.Prompt)}
+	for _, img := range req.Images {
+		parts = append(parts, genai.Blob{MIMEType: img.MIMEType, Data: img.Data})
+	}
+	return parts
+}
+
+func (g *Gemini) Generate(ctx context.Context, req Request) (*Response, error) {
+	resp, err := g.chatSession(req).SendMessage(ctx, promptParts(req)...)
+	if err != nil {
+		return nil, err
+	}
+	return toResponse(resp), nil
+}
+
+func (g *Gemini) Stream(ctx context.Context, req Request, fn ChunkFunc) (*Response, error) {
+	iter := g.chatSession(req).SendMessageStream(ctx, promptParts(req)...)
+
+	var text strings.Builder
+	result := &Response{}
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return partial(result, text.String()), err
+		}
+
+		// Usage is reported as a running total, the last chunk has the final count
+		chunk := toResponse(resp)
+		if chunk.PromptTokens > 0 || chunk.OutputTokens > 0 {
+			result.PromptTokens, result.OutputTokens = chunk.PromptTokens, chunk.OutputTokens
+		}
+		if chunk.Text == "" {
+			continue
+		}
+		text.WriteString(chunk.Text)
+		if err := fn(chunk.Text); err != nil {
+			return partial(result, text.String()), err
+		}
+	}
+
+	result.Text = text.String()
+	return result, nil
+}
+
+func toResponse(resp *genai.GenerateContentResponse) *Response {
+	var text strings.Builder
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if t, ok := part.(genai.Text); ok {
+				text.WriteString(string(t))
+			}
+		}
+	}
+
+	result := &Response{Text: text.String()}
+	if resp.UsageMetadata != nil {
+		result.PromptTokens = int(resp.UsageMetadata.PromptTokenCount)
+		result.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	return result
+}
+
+var genaiTypes = map[string]genai.Type{
+	TypeObject:  genai.TypeObject,
+	TypeArray:   genai.TypeArray,
+	TypeString:  genai.TypeString,
+	TypeInteger: genai.TypeInteger,
+	TypeNumber:  genai.TypeNumber,
+}
+
+func toGenaiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	result := &genai.Schema{
+		Type:        genaiTypes[s.Type],
+		Description: s.Description,
+		Items:       toGenaiSchema(s.Items),
+		Required:    s.Required,
+	}
+	if len(s.Properties) > 0 {
+		result.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			result.Properties[name] = toGenaiSchema(prop)
+		}
+	}
+	return result
+}
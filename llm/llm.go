@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Schema describes the JSON a caller expects back. It is a subset of JSON
+// Schema that every provider can express.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// Schema types.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+)
+
+// Message roles in a conversation history.
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// Message is one earlier turn of a conversation.
+type Message struct {
+	Role string
+	Text string
+}
+
+// Image is a picture sent along with the prompt to a multimodal model.
+type Image struct {
+	// MIMEType is e.g. "image/jpeg" or "image/png".
+	MIMEType string
+	Data     []byte
+}
+
+// Request is a single generation request.
+type Request struct {
+	// System is an optional instruction that takes precedence over Prompt.
+	System string
+	// History holds the earlier turns of a conversation, oldest first.
+	History []Message
+	Prompt  string
+	// Images are sent with the prompt; not every model accepts them.
+	Images []Image
+	// Schema, when set, asks for a JSON answer matching it.
+	Schema *Schema
+}
+
+// Response is the generated text and the tokens it cost.
+type Response struct {
+	Text         string
+	PromptTokens int
+	OutputTokens int
+}
+
+// partial returns what a stream generated before it stopped, or nil when
+// it generated nothing.
+func partial(resp *Response, text string) *Response {
+	if text == "" && resp.OutputTokens == 0 {
+		return nil
+	}
+	return &Response{Text: text, PromptTokens: resp.PromptTokens, OutputTokens: resp.OutputTokens}
+}
+
+// ChunkFunc receives generated text as it arrives. Returning an error stops
+// the generation.
+type ChunkFunc func(chunk string) error
+
+// Provider generates text with a language model.
+type Provider interface {
+	Name() string
+	Generate(ctx context.Context, req Request) (*Response, error)
+	// Stream generates like Generate, passing the text to fn piece by piece
+	// as the model produces it. The returned Response holds the full text.
+	// When the stream stops early, the Response returned with the error
+	// holds what was generated so far, or is nil when nothing was.
+	Stream(ctx context.Context, req Request, fn ChunkFunc) (*Response, error)
+}
+
+var provider Provider
+
+// Setup creates the provider selected by LLM_PROVIDER (gemini, openai or
+// fake, defaulting to gemini). The provider is shared by all requests.
+func Setup() error {
+	var err error
+	switch name := os.Getenv("LLM_PROVIDER"); name {
+	case "", "gemini":
+		provider, err = NewGemini(context.Background(), os.Getenv("Genai_API_KEY"), getenv("GEMINI_MODEL", "gemini-1.5-flash"))
+	case "openai":
+		provider = NewOpenAI(getenv("OPENAI_BASE_URL", "http://localhost:11434/v1"), os.Getenv("OPENAI_API_KEY"), getenv("OPENAI_MODEL", "llama3.1"))
+	case "fake":
+		provider = NewFake()
+	default:
+		return fmt.Errorf("unknown LLM_PROVIDER %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating %s provider: %v", os.Getenv("LLM_PROVIDER"), err)
+	}
+
+	log.Printf("Using %s language model provider", provider.Name())
+	return nil
+}
+
+// Get returns the provider created by Setup.
+func Get() Provider {
+	return provider
+}
+
+// SetProvider replaces the shared provider, e.g. with a fake.
+func SetProvider(p Provider) {
+	provider = p
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,230 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI talks to any OpenAI compatible chat completions endpoint, such as
+// a local Ollama server at http://localhost:11434/v1.
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAI(baseURL, apiKey, model string) *OpenAI {
+	return &OpenAI{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (o *OpenAI) Name() string {
+	return "openai"
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAIRequestMessage is a message sent to the server. Content is either
+// a string or, for messages with images, a list of content parts.
+type openAIRequestMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type openAIContentPart struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	ImageURL map[string]string `json:"image_url,omitempty"`
+}
+
+type openAIRequest struct {
+	Model          string                 `json:"model"`
+	Messages       []openAIRequestMessage `json:"messages"`
+	ResponseFormat map[string]string      `json:"response_format,omitempty"`
+	Stream         bool                   `json:"stream,omitempty"`
+	StreamOptions  map[string]bool        `json:"stream_options,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// openAIChunk is one server-sent event of a streamed chat completion.
+type openAIChunk struct {
+	Choices []struct {
+		Delta openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// newRequest builds the chat completion body. Not every compatible server
+// supports JSON schemas, so the schema is described in the system message
+// and only JSON mode is requested.
+func (o *OpenAI) newRequest(req Request) (openAIRequest, error) {
+	body := openAIRequest{Model: o.model}
+
+	system := req.System
+	if req.Schema != nil {
+		schema, err := json.Marshal(req.Schema)
+		if err != nil {
+			return body, err
+		}
+		system = strings.TrimSpace(system + "\nAnswer only with JSON matching this JSON schema: " + string(schema))
+		body.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+	if system != "" {
+		body.Messages = append(body.Messages, openAIRequestMessage{Role: "system", Content: system})
+	}
+	for _, msg := range req.History {
+		role := "user"
+		if msg.Role == RoleModel {
+			role = "assistant"
+		}
+		body.Messages = append(body.Messages, openAIRequestMessage{Role: role, Content: msg.Text})
+	}
+
+	if len(req.Images) == 0 {
+		body.Messages = append(body.Messages, openAIRequestMessage{Role: "user", Content: req.Prompt})
+		return body, nil
+	}
+
+	// Images are sent inline as data URLs
+	parts := []openAIContentPart{{Type: "text", Text: req.Prompt}}
+	for _, img := range req.Images {
+		url := "data:" + img.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: map[string]string{"url": url}})
+	}
+	body.Messages = append(body.Messages, openAIRequestMessage{Role: "user", Content: parts})
+	return body, nil
+}
+
+func (o *OpenAI) post(ctx context.Context, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("chat completion failed with status %d: %s", resp.StatusCode, msg)
+	}
+	return resp, nil
+}
+
+func (o *OpenAI) Generate(ctx context.Context, req Request) (*Response, error) {
+	body, err := o.newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion returned no choices")
+	}
+
+	return &Response{
+		Text:         result.Choices[0].Message.Content,
+		PromptTokens: result.Usage.PromptTokens,
+		OutputTokens: result.Usage.CompletionTokens,
+	}, nil
+}
+
+func (o *OpenAI) Stream(ctx context.Context, req Request, fn ChunkFunc) (*Response, error) {
+	body, err := o.newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = true
+	body.StreamOptions = map[string]bool{"include_usage": true}
+
+	resp, err := o.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var text strings.Builder
+	result := &Response{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return partial(result, text.String()), err
+		}
+		if chunk.Usage != nil {
+			result.PromptTokens = chunk.Usage.PromptTokens
+			result.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		if err := fn(chunk.Choices[0].Delta.Content); err != nil {
+			return partial(result, text.String()), err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return partial(result, text.String()), err
+	}
+
+	result.Text = text.String()
+	return result, nil
+}
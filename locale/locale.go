@@ -0,0 +1,132 @@
+package locale
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages. Indonesian is the default because most users are
+// Indonesian.
+const (
+	Indonesian = "id"
+	English    = "en"
+	Default    = Indonesian
+)
+
+var messages = map[string]map[string]string{
+	Indonesian: {
+		"invalid_payload":       "Format permintaan tidak valid",
+		"invalid_date":          "Format tanggal tidak valid, gunakan YYYY-MM-DD",
+		"missing_user_or_date":  "user_id atau meal_date belum diisi",
+		"db_not_initialized":    "Koneksi database belum siap",
+		"food_list_failed":      "Gagal mengambil daftar makanan",
+		"food_tags_failed":      "Gagal mengambil tag makanan",
+		"food_scan_failed":      "Gagal membaca data makanan",
+		"food_iterate_failed":   "Gagal membaca daftar makanan",
+		"marshal_failed":        "Gagal menyusun respons",
+		"track_id_failed":       "Gagal mengambil catatan makan harian",
+		"meals_failed":          "Gagal mengambil daftar makanan yang dicatat",
+		"search_failed":         "Gagal mencari artikel",
+		"missing_food_id":       "food_id belum diisi",
+		"invalid_food":          "food_id tidak ada di katalog",
+		"invalid_servings":      "servings harus lebih dari 0 dan paling banyak 20",
+		"conflict_check_failed": "Gagal memeriksa pantangan makanan",
+		"allergy_conflict":      "Makanan mengandung alergen Anda, kirim ulang dengan force untuk tetap mencatatnya",
+		"log_meal_failed":       "Gagal mencatat makanan",
+		"meal_logged":           "Makanan berhasil dicatat",
+		"meal_not_found":        "Tidak ada catatan makan pada tanggal tersebut",
+		"meal_detail_not_found": "Makanan tidak ditemukan dalam catatan",
+		"delete_meal_failed":    "Gagal menghapus makanan",
+		"meal_deleted":          "Makanan berhasil dihapus",
+		"medical_disclaimer":    "Saran ini dibuat oleh AI sebagai informasi umum dan bukan pengganti nasihat dokter atau ahli gizi. Konsultasikan dahulu jika Anda hamil, menyusui, atau memiliki kondisi medis.",
+	},
+	English: {
+		"invalid_payload":       "Invalid request payload",
+		"invalid_date":          "Invalid date format, please use YYYY-MM-DD",
+		"missing_user_or_date":  "Missing user_id or meal_date",
+		"db_not_initialized":    "Database connection is not initialized",
+		"food_list_failed":      "Failed to retrieve food list",
+		"food_tags_failed":      "Failed to retrieve food tags",
+		"food_scan_failed":      "Failed to scan food item",
+		"food_iterate_failed":   "Failed to iterate through food list",
+		"marshal_failed":        "Failed to marshal response",
+		"track_id_failed":       "Failed to retrieve track ID",
+		"meals_failed":          "Failed to retrieve meals",
+		"search_failed":         "Failed to search articles",
+		"missing_food_id":       "Missing food_id",
+		"invalid_food":          "food_id is not in the catalogue",
+		"invalid_servings":      "servings must be more than 0 and at most 20",
+		"conflict_check_failed": "Failed to check dietary conflicts",
+		"allergy_conflict":      "Food conflicts with the user's allergies, resend with force to log it anyway",
+		"log_meal_failed":       "Failed to log meal",
+		"meal_logged":           "Meal logged successfully",
+		"meal_not_found":        "No meal found for the given date",
+		"meal_detail_not_found": "No matching meal detail found",
+		"delete_meal_failed":    "Failed to delete meal detail",
+		"meal_deleted":          "Meal detail deleted successfully",
+		"medical_disclaimer":    "This AI-generated advice is general information and not a substitute for advice from a doctor or dietitian. Consult one first if you are pregnant, breastfeeding or have a medical condition.",
+	},
+}
+
+// FromRequest picks the best supported language from the Accept-Language
+// header, honouring quality values, and falls back to Default.
+func FromRequest(r *http.Request) string {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return Default
+	}
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		// Only the primary subtag matters, "en-US" is treated as "en"
+		lang = strings.SplitN(lang, "-", 2)[0]
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		candidates = append(candidates, candidate{lang, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	for _, c := range candidates {
+		if _, ok := messages[c.lang]; ok && c.q > 0 {
+			return c.lang
+		}
+	}
+	return Default
+}
+
+// IsSupported reports whether lang has a message catalogue.
+func IsSupported(lang string) bool {
+	_, ok := messages[lang]
+	return ok
+}
+
+// Message returns the message for key in lang, falling back to the default
+// language and finally to the key itself.
+func Message(lang, key string) string {
+	if msg, ok := messages[lang][key]; ok {
+		return msg
+	}
+	if msg, ok := messages[Default][key]; ok {
+		return msg
+	}
+	return key
+}
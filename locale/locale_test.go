@@ -0,0 +1,62 @@
+package locale
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", Default},
+		{"en", English},
+		{"en-US,en;q=0.9", English},
+		{"id-ID", Indonesian},
+		{"fr", Default},
+		{"fr, en;q=0.5", English},
+		{"en;q=0.3, id;q=0.8", Indonesian},
+		{"id;q=0, en;q=0.1", English},
+		{"EN-gb", English},
+		{"en;q=abc", English},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Accept-Language", tt.header)
+		}
+		if got := FromRequest(r); got != tt.want {
+			t.Errorf("FromRequest(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		lang, key, want string
+	}{
+		{English, "invalid_date", "Invalid date format, please use YYYY-MM-DD"},
+		{Indonesian, "invalid_date", "Format tanggal tidak valid, gunakan YYYY-MM-DD"},
+		{"fr", "invalid_date", "Format tanggal tidak valid, gunakan YYYY-MM-DD"},
+		{English, "no_such_key", "no_such_key"},
+	}
+	for _, tt := range tests {
+		if got := Message(tt.lang, tt.key); got != tt.want {
+			t.Errorf("Message(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
+
+// Every message must exist in every language.
+func TestCataloguesComplete(t *testing.T) {
+	for lang, catalogue := range messages {
+		for other, otherCatalogue := range messages {
+			for key := range otherCatalogue {
+				if _, ok := catalogue[key]; !ok {
+					t.Errorf("%q is in %s but missing from %s", key, other, lang)
+				}
+			}
+		}
+	}
+}
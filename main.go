@@ -5,12 +5,16 @@ import (
 
 	"log"
 	"net/http"
+	"nutrishe/auth"
 	"nutrishe/controllers/april"
+	"os"
 
 	"nutrishe/controllers/artikel"
 	"nutrishe/controllers/mealtrackcontroller"
 	"nutrishe/controllers/nabila"
 	"nutrishe/controllers/recommendmeals"
+	"nutrishe/llm"
+	"nutrishe/search"
 
 	"nutrishe/models"
 
@@ -24,12 +28,30 @@ func main() {
 		log.Fatalf("Error loading environment variables: %v", err)
 	}
 
+	// Tokens must not be signed with an empty key
+	err = auth.SetKey(os.Getenv("JWT_KEY"))
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+
 	// Setup database
 	err = models.Setup()
 	if err != nil {
 		log.Fatalf("Failed to set up database: %v", err)
 	}
 
+	// Setup the language model used for AI features
+	err = llm.Setup()
+	if err != nil {
+		log.Fatalf("Failed to set up language model: %v", err)
+	}
+
+	// Setup article search
+	err = search.Setup()
+	if err != nil {
+		log.Fatalf("Failed to set up article search: %v", err)
+	}
+
 	// Create a new ServeMux
 	mux := http.NewServeMux()
 
@@ -41,19 +63,61 @@ func main() {
 	mux.HandleFunc("/register", nabila.Register)
 	mux.HandleFunc("/login", nabila.Login)
 	mux.HandleFunc("/dietplan", nabila.CreateDietPlan)
+	mux.HandleFunc("/dietplans", nabila.ListDietPlans)
+	mux.HandleFunc("/update_dietplan", nabila.UpdateDietPlan)
+	mux.HandleFunc("/end_dietplan", nabila.EndDietPlan)
+	mux.HandleFunc("/delete_dietplan", nabila.DeleteDietPlan)
+	mux.HandleFunc("/dietplan_goal", nabila.ViewCaloriesGoal)
+	mux.HandleFunc("/dietplan_progress", nabila.ViewDietPlanProgress)
+	mux.HandleFunc("/plan_templates", nabila.ListPlanTemplates)
+	mux.HandleFunc("/plan_template", nabila.GetPlanTemplate)
+	mux.Handle("/create_plan_template", auth.Middleware(http.HandlerFunc(nabila.CreatePlanTemplate)))
+	mux.HandleFunc("/adopt_plan_template", nabila.AdoptPlanTemplate)
 	mux.HandleFunc("/calculate", nabila.CalculateCalories)
 	mux.HandleFunc("/calories_goal", nabila.GetCalorieDataHandler)
 	mux.HandleFunc("/monthly_calories", nabila.ViewMonthlyCalories)
+	mux.HandleFunc("/nutrition_summary", nabila.ViewNutritionSummary)
+	mux.HandleFunc("/preferences", nabila.GetPreferences)
+	mux.HandleFunc("/update_preferences", nabila.UpdatePreferences)
 	mux.HandleFunc("/dailymeal", april.LogMeal)
 	mux.HandleFunc("/food", april.GetFoodList)
 	mux.HandleFunc("/mealdetail", april.GetMealsByDate)
 	mux.HandleFunc("/deletemealdetail", april.DeleteMealDetail)
 
 	mux.HandleFunc("/add_meal", mealtrackcontroller.AddMeal)
+	mux.HandleFunc("/food_tags", mealtrackcontroller.UpdateFoodTags)
+	mux.HandleFunc("/food_price", mealtrackcontroller.UpdateFoodPrice)
 
-	mux.HandleFunc("/recommend_meals", recommendmeals.RecommendMeals)
+	mux.Handle("/recommend_meals", auth.Middleware(http.HandlerFunc(recommendmeals.RecommendMeals)))
+	mux.Handle("/recommend_meals/stream", auth.Middleware(http.HandlerFunc(recommendmeals.RecommendMealsStream)))
+	mux.Handle("/ai_quota", auth.Middleware(http.HandlerFunc(recommendmeals.GetAIQuota)))
+	mux.Handle("/meal_photo", auth.Middleware(http.HandlerFunc(recommendmeals.RecognizeMealPhoto)))
+	mux.Handle("/meal_text", auth.Middleware(http.HandlerFunc(recommendmeals.ParseMealText)))
+	mux.Handle("/chat", auth.Middleware(http.HandlerFunc(recommendmeals.Chat)))
+	mux.Handle("/chat_threads", auth.Middleware(http.HandlerFunc(recommendmeals.ListChatThreads)))
+	mux.Handle("/chat_thread", auth.Middleware(http.HandlerFunc(recommendmeals.GetChatThread)))
+	mux.Handle("/delete_chat_thread", auth.Middleware(http.HandlerFunc(recommendmeals.DeleteChatThread)))
+	mux.Handle("/accept_meal_plan", auth.Middleware(http.HandlerFunc(recommendmeals.AcceptMealPlan)))
+	mux.Handle("/planned_meals", auth.Middleware(http.HandlerFunc(recommendmeals.GetPlannedMeals)))
+	mux.Handle("/mark_meal_eaten", auth.Middleware(http.HandlerFunc(recommendmeals.MarkMealEaten)))
+	mux.Handle("/shopping_list", auth.Middleware(http.HandlerFunc(recommendmeals.ShoppingList)))
+	mux.Handle("/pantry", auth.Middleware(http.HandlerFunc(recommendmeals.GetPantry)))
+	mux.Handle("/update_pantry", auth.Middleware(http.HandlerFunc(recommendmeals.UpdatePantry)))
 
 	mux.HandleFunc("/search_articles", artikel.SearchArticles)
+	mux.HandleFunc("/articles", artikel.ListArticles)
+	mux.HandleFunc("/article", artikel.GetArticle)
+	mux.Handle("/create_article", auth.Middleware(http.HandlerFunc(artikel.CreateArticle)))
+	mux.Handle("/update_article", auth.Middleware(http.HandlerFunc(artikel.UpdateArticle)))
+	mux.Handle("/publish_article", auth.Middleware(http.HandlerFunc(artikel.PublishArticle)))
+	mux.Handle("/my_articles", auth.Middleware(http.HandlerFunc(artikel.ListMyArticles)))
+	mux.Handle("/bookmark_article", auth.Middleware(http.HandlerFunc(artikel.BookmarkArticle)))
+	mux.Handle("/remove_bookmark", auth.Middleware(http.HandlerFunc(artikel.RemoveBookmark)))
+	mux.Handle("/bookmarks", auth.Middleware(http.HandlerFunc(artikel.ListBookmarks)))
+	mux.Handle("/article_reminders", auth.Middleware(http.HandlerFunc(artikel.ListReminders)))
+	mux.Handle("/read_article", auth.Middleware(http.HandlerFunc(artikel.ReadArticle)))
+	mux.Handle("/reading_history", auth.Middleware(http.HandlerFunc(artikel.ReadingHistory)))
+	mux.Handle("/recommend_articles", auth.Middleware(http.HandlerFunc(artikel.RecommendArticles)))
 
 	mux.HandleFunc("/logout", nabila.Logout)
 	// Start the HTTP server
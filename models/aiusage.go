@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Default number of AI requests a user may make, overridable with the
+// AI_DAILY_QUOTA and AI_MONTHLY_QUOTA environment variables.
+const (
+	defaultDailyAIQuota   = 20
+	defaultMonthlyAIQuota = 300
+)
+
+// ErrAIQuotaExceeded is returned by ReserveAIUsage when the user has no AI
+// requests left.
+var ErrAIQuotaExceeded = errors.New("AI request quota exceeded")
+
+// AIUsage is the AI usage of a user over a period. Meal plans served from
+// the cache are counted but do not use up the quota.
+type AIUsage struct {
+	Requests       int `json:"requests"`
+	CachedRequests int `json:"cached_requests"`
+	PromptTokens   int `json:"prompt_tokens"`
+	OutputTokens   int `json:"output_tokens"`
+}
+
+// AIQuota is a user's usage and remaining requests for today and this month.
+type AIQuota struct {
+	DailyLimit      int     `json:"daily_limit"`
+	MonthlyLimit    int     `json:"monthly_limit"`
+	Today           AIUsage `json:"today"`
+	ThisMonth       AIUsage `json:"this_month"`
+	RemainingToday  int     `json:"remaining_today"`
+	RemainingMonth  int     `json:"remaining_month"`
+	DailyResetsAt   string  `json:"daily_resets_at"`
+	MonthlyResetsAt string  `json:"monthly_resets_at"`
+}
+
+// Exceeded reports whether the user has no requests left.
+func (q AIQuota) Exceeded() bool {
+	return q.RemainingToday <= 0 || q.RemainingMonth <= 0
+}
+
+func quotaLimit(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n >= 0 {
+		return n
+	}
+	return fallback
+}
+
+// RecordAIUsage stores one AI request made by a user, e.g. one answered
+// from the cache.
+func RecordAIUsage(db *sql.DB, userID, endpoint string, promptTokens, outputTokens int, cached bool) error {
+	query := "INSERT INTO ai_usage (UserID, Endpoint, PromptTokens, OutputTokens, Cached, CreatedAt) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(query, userID, endpoint, promptTokens, outputTokens, cached, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// ReserveAIUsage checks the user's quota and, when requests are left, stores
+// a usage row for a request about to be made, so that parallel requests
+// cannot all pass the check. The row is completed with UpdateAIUsage, or
+// removed with ReleaseAIUsage when nothing was generated. When the quota is
+// used up it returns the quota and ErrAIQuotaExceeded.
+func ReserveAIUsage(db *sql.DB, userID, endpoint string, now time.Time) (int64, *AIQuota, error) {
+	tx, err := lockUser(db, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	quota, err := getAIQuota(tx, userID, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	if quota.Exceeded() {
+		tx.Rollback()
+		return 0, quota, ErrAIQuotaExceeded
+	}
+
+	query := "INSERT INTO ai_usage (UserID, Endpoint, PromptTokens, OutputTokens, Cached, CreatedAt) VALUES (?, ?, 0, 0, 0, ?)"
+	result, err := tx.Exec(query, userID, endpoint, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	usageID, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return usageID, quota, nil
+}
+
+// UpdateAIUsage stores the cost of a reserved request.
+func UpdateAIUsage(db *sql.DB, usageID int64, promptTokens, outputTokens int) error {
+	_, err := db.Exec("UPDATE ai_usage SET PromptTokens = ?, OutputTokens = ? WHERE UsageID = ?", promptTokens, outputTokens, usageID)
+	return err
+}
+
+// ReleaseAIUsage removes a reserved request that generated nothing.
+func ReleaseAIUsage(db *sql.DB, usageID int64) error {
+	_, err := db.Exec("DELETE FROM ai_usage WHERE UsageID = ?", usageID)
+	return err
+}
+
+func getAIUsage(db rowQuerier, userID string, since time.Time) (AIUsage, error) {
+	var usage AIUsage
+	query := `SELECT COUNT(*), COALESCE(SUM(Cached), 0), COALESCE(SUM(PromptTokens), 0), COALESCE(SUM(OutputTokens), 0)
+	          FROM ai_usage WHERE UserID = ? AND CreatedAt >= ?`
+	err := db.QueryRow(query, userID, since.Format("2006-01-02 15:04:05")).
+		Scan(&usage.Requests, &usage.CachedRequests, &usage.PromptTokens, &usage.OutputTokens)
+	return usage, err
+}
+
+// GetAIQuota returns the usage and remaining quota of a user at now. Days and
+// months follow the server's local time.
+func GetAIQuota(db *sql.DB, userID string, now time.Time) (*AIQuota, error) {
+	return getAIQuota(db, userID, now)
+}
+
+func getAIQuota(db rowQuerier, userID string, now time.Time) (*AIQuota, error) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	quota := &AIQuota{
+		DailyLimit:      quotaLimit("AI_DAILY_QUOTA", defaultDailyAIQuota),
+		MonthlyLimit:    quotaLimit("AI_MONTHLY_QUOTA", defaultMonthlyAIQuota),
+		DailyResetsAt:   dayStart.AddDate(0, 0, 1).Format(time.RFC3339),
+		MonthlyResetsAt: monthStart.AddDate(0, 1, 0).Format(time.RFC3339),
+	}
+
+	var err error
+	if quota.Today, err = getAIUsage(db, userID, dayStart); err != nil {
+		return nil, err
+	}
+	if quota.ThisMonth, err = getAIUsage(db, userID, monthStart); err != nil {
+		return nil, err
+	}
+
+	quota.RemainingToday = quota.DailyLimit - (quota.Today.Requests - quota.Today.CachedRequests)
+	if quota.RemainingToday < 0 {
+		quota.RemainingToday = 0
+	}
+	quota.RemainingMonth = quota.MonthlyLimit - (quota.ThisMonth.Requests - quota.ThisMonth.CachedRequests)
+	if quota.RemainingMonth < 0 {
+		quota.RemainingMonth = 0
+	}
+	return quota, nil
+}
@@ -0,0 +1,373 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Article statuses. Only published articles are visible to users.
+const (
+	ArticleDraft     = "draft"
+	ArticlePublished = "published"
+)
+
+// Kinds of article labels.
+const (
+	LabelTag       = "tag"
+	LabelPhase     = "phase"
+	LabelCondition = "condition"
+)
+
+// Conditions an article can be relevant for.
+var ArticleConditions = []string{"anemia", "pcos", "pms", "pregnancy", "breastfeeding", "diabetes", "hypertension", "weight_loss"}
+
+var cyclePhases = []string{PhaseMenstrual, PhaseFollicular, PhaseOvulation, PhaseLuteal}
+
+// ErrArticleNotFound is returned when an article does not exist.
+var ErrArticleNotFound = errors.New("article not found")
+
+// Article is an in-house article written by a nutritionist. Body is
+// Markdown. Phases and Conditions say who the article is most relevant for.
+type Article struct {
+	ArticleID   int        `json:"article_id"`
+	Title       string     `json:"title"`
+	Summary     string     `json:"summary"`
+	Body        string     `json:"body,omitempty"`
+	Lang        string     `json:"lang"`
+	AuthorID    string     `json:"author_id"`
+	Status      string     `json:"status"`
+	Tags        []string   `json:"tags"`
+	Phases      []string   `json:"phases"`
+	Conditions  []string   `json:"conditions"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+func normalizeLabels(values, allowed []string, kind string) ([]string, error) {
+	seen := make(map[string]bool)
+	labels := []string{}
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		if allowed != nil && !contains(allowed, v) {
+			return nil, fmt.Errorf("unknown %s %q", kind, v)
+		}
+		if len(v) > 30 {
+			return nil, fmt.Errorf("%s %q is too long", kind, v)
+		}
+		seen[v] = true
+		labels = append(labels, v)
+	}
+	return labels, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Lengths of the article's Title and Summary columns, in characters.
+const (
+	maxArticleTitle   = 255
+	maxArticleSummary = 500
+)
+
+// NormalizeArticle checks an article's labels and lengths and fills in the
+// summary from the body when it is empty.
+func NormalizeArticle(a *Article) error {
+	var err error
+	if a.Tags, err = normalizeLabels(a.Tags, nil, "tag"); err != nil {
+		return err
+	}
+	if a.Phases, err = normalizeLabels(a.Phases, cyclePhases, "cycle phase"); err != nil {
+		return err
+	}
+	if a.Conditions, err = normalizeLabels(a.Conditions, ArticleConditions, "condition"); err != nil {
+		return err
+	}
+
+	a.Title = strings.TrimSpace(a.Title)
+	if utf8.RuneCountInString(a.Title) > maxArticleTitle {
+		return fmt.Errorf("title must be at most %d characters", maxArticleTitle)
+	}
+	a.Summary = strings.TrimSpace(a.Summary)
+	if utf8.RuneCountInString(a.Summary) > maxArticleSummary {
+		return fmt.Errorf("summary must be at most %d characters", maxArticleSummary)
+	}
+	if a.Summary == "" {
+		a.Summary = summarizeMarkdown(a.Body)
+	}
+	return nil
+}
+
+// summarizeMarkdown returns the start of the body as plain text.
+func summarizeMarkdown(body string) string {
+	text := strings.Map(func(r rune) rune {
+		if strings.ContainsRune("#*_`>[]", r) {
+			return -1
+		}
+		return r
+	}, body)
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 200 {
+		text = string(runes[:197]) + "..."
+	}
+	return text
+}
+
+func saveArticleLabels(tx *sql.Tx, a *Article) error {
+	_, err := tx.Exec("DELETE FROM article_label WHERE ArticleID = ?", a.ArticleID)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string][]string{LabelTag: a.Tags, LabelPhase: a.Phases, LabelCondition: a.Conditions}
+	for kind, values := range labels {
+		for _, v := range values {
+			_, err = tx.Exec("INSERT INTO article_label (ArticleID, Kind, Value) VALUES (?, ?, ?)", a.ArticleID, kind, v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CreateArticle stores a new draft article, assigning its ArticleID.
+func CreateArticle(db *sql.DB, a *Article) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	a.Status = ArticleDraft
+	a.CreatedAt, a.UpdatedAt, a.PublishedAt = now, now, nil
+	query := `INSERT INTO article (Title, Summary, Body, Lang, AuthorID, Status, CreatedAt, UpdatedAt)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, a.Title, a.Summary, a.Body, a.Lang, a.AuthorID, a.Status, now.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	a.ArticleID = int(id)
+
+	if err := saveArticleLabels(tx, a); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateArticle changes the content and labels of an article, keeping its
+// status.
+func UpdateArticle(db *sql.DB, a *Article) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	a.UpdatedAt = time.Now()
+	query := "UPDATE article SET Title = ?, Summary = ?, Body = ?, Lang = ?, UpdatedAt = ? WHERE ArticleID = ?"
+	_, err = tx.Exec(query, a.Title, a.Summary, a.Body, a.Lang, a.UpdatedAt.Format("2006-01-02 15:04:05"), a.ArticleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := saveArticleLabels(tx, a); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetArticlePublished publishes an article, or takes it back to draft.
+func SetArticlePublished(db *sql.DB, a *Article, publish bool) error {
+	now := time.Now()
+	a.Status = ArticleDraft
+	var publishedAt interface{}
+	if publish {
+		a.Status = ArticlePublished
+		// Republishing keeps the original date
+		if a.PublishedAt == nil {
+			a.PublishedAt = &now
+		}
+		publishedAt = a.PublishedAt.Format("2006-01-02 15:04:05")
+	} else {
+		a.PublishedAt = nil
+	}
+	a.UpdatedAt = now
+
+	_, err := db.Exec("UPDATE article SET Status = ?, PublishedAt = ?, UpdatedAt = ? WHERE ArticleID = ?",
+		a.Status, publishedAt, now.Format("2006-01-02 15:04:05"), a.ArticleID)
+	return err
+}
+
+const articleColumns = "ArticleID, Title, Summary, Body, Lang, AuthorID, Status, CreatedAt, UpdatedAt, PublishedAt"
+
+func scanArticle(row interface{ Scan(...interface{}) error }) (*Article, error) {
+	var a Article
+	var createdAt, updatedAt string
+	var publishedAt sql.NullString
+	err := row.Scan(&a.ArticleID, &a.Title, &a.Summary, &a.Body, &a.Lang, &a.AuthorID, &a.Status, &createdAt, &updatedAt, &publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if a.CreatedAt, err = parseDBDate(createdAt); err != nil {
+		return nil, err
+	}
+	if a.UpdatedAt, err = parseDBDate(updatedAt); err != nil {
+		return nil, err
+	}
+	if publishedAt.Valid {
+		t, err := parseDBDate(publishedAt.String)
+		if err != nil {
+			return nil, err
+		}
+		a.PublishedAt = &t
+	}
+	a.Tags, a.Phases, a.Conditions = []string{}, []string{}, []string{}
+	return &a, nil
+}
+
+// loadArticleLabels fills in the labels of articles.
+func loadArticleLabels(db *sql.DB, articles []Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int)
+	ids := make([]interface{}, len(articles))
+	for i, a := range articles {
+		index[a.ArticleID] = i
+		ids[i] = a.ArticleID
+	}
+
+	query := "SELECT ArticleID, Kind, Value FROM article_label WHERE ArticleID IN (?" + strings.Repeat(", ?", len(ids)-1) + ") ORDER BY Value"
+	rows, err := db.Query(query, ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var kind, value string
+		if err := rows.Scan(&id, &kind, &value); err != nil {
+			return err
+		}
+		a := &articles[index[id]]
+		switch kind {
+		case LabelTag:
+			a.Tags = append(a.Tags, value)
+		case LabelPhase:
+			a.Phases = append(a.Phases, value)
+		case LabelCondition:
+			a.Conditions = append(a.Conditions, value)
+		}
+	}
+	return rows.Err()
+}
+
+func queryArticles(db *sql.DB, query string, args ...interface{}) ([]Article, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	articles := []Article{}
+	for rows.Next() {
+		a, err := scanArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return articles, loadArticleLabels(db, articles)
+}
+
+// GetArticle returns an article by ID, or ErrArticleNotFound.
+func GetArticle(db *sql.DB, articleID int) (*Article, error) {
+	articles, err := queryArticles(db, "SELECT "+articleColumns+" FROM article WHERE ArticleID = ?", articleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return nil, ErrArticleNotFound
+	}
+	return &articles[0], nil
+}
+
+// ArticleFilter selects published articles. Empty fields match everything.
+type ArticleFilter struct {
+	Lang      string
+	Tag       string
+	Phase     string
+	Condition string
+}
+
+// ListPublishedArticles returns published articles, newest first, without
+// their bodies.
+func ListPublishedArticles(db *sql.DB, filter ArticleFilter) ([]Article, error) {
+	query := "SELECT " + articleColumns + " FROM article a WHERE Status = ?"
+	args := []interface{}{ArticlePublished}
+	if filter.Lang != "" {
+		query += " AND Lang = ?"
+		args = append(args, filter.Lang)
+	}
+	for kind, value := range map[string]string{LabelTag: filter.Tag, LabelPhase: filter.Phase, LabelCondition: filter.Condition} {
+		if value != "" {
+			query += " AND EXISTS (SELECT 1 FROM article_label l WHERE l.ArticleID = a.ArticleID AND l.Kind = ? AND l.Value = ?)"
+			args = append(args, kind, strings.ToLower(value))
+		}
+	}
+	query += " ORDER BY PublishedAt DESC"
+
+	articles, err := queryArticles(db, query, args...)
+	for i := range articles {
+		articles[i].Body = ""
+	}
+	return articles, err
+}
+
+// ListArticlesByAuthor returns all articles of an author, drafts included.
+func ListArticlesByAuthor(db *sql.DB, authorID string) ([]Article, error) {
+	return queryArticles(db, "SELECT "+articleColumns+" FROM article WHERE AuthorID = ? ORDER BY UpdatedAt DESC", authorID)
+}
+
+// SearchPublishedArticles finds published articles with MySQL full-text
+// search, best match first, without their bodies.
+func SearchPublishedArticles(db *sql.DB, text, lang string, limit int) ([]Article, error) {
+	query := "SELECT " + articleColumns + " FROM article WHERE Status = ? AND Lang = ? AND MATCH (Title, Summary, Body) AGAINST (?)" +
+		" ORDER BY MATCH (Title, Summary, Body) AGAINST (?) DESC LIMIT ?"
+	articles, err := queryArticles(db, query, ArticlePublished, lang, text, text, limit)
+	for i := range articles {
+		articles[i].Body = ""
+	}
+	return articles, err
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeArticle(t *testing.T) {
+	tests := []struct {
+		name        string
+		article     Article
+		wantErr     string
+		wantSummary string
+	}{
+		{name: "summary from body", article: Article{Title: " Zat besi ", Body: "# Zat besi\n\n**Bayam** kaya zat besi."}, wantSummary: "Zat besi Bayam kaya zat besi."},
+		{name: "longest title", article: Article{Title: strings.Repeat("é", 255), Summary: "ok"}, wantSummary: "ok"},
+		{name: "title too long", article: Article{Title: strings.Repeat("a", 256)}, wantErr: "title"},
+		{name: "summary too long", article: Article{Title: "ok", Summary: strings.Repeat("a", 501)}, wantErr: "summary"},
+		{name: "unknown phase", article: Article{Title: "ok", Phases: []string{"winter"}}, wantErr: "cycle phase"},
+		{name: "tag too long", article: Article{Title: "ok", Tags: []string{strings.Repeat("t", 31)}}, wantErr: "too long"},
+	}
+	for _, tt := range tests {
+		a := tt.article
+		err := NormalizeArticle(&a)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error %v, want one mentioning %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if a.Summary != tt.wantSummary {
+			t.Errorf("%s: summary %q, want %q", tt.name, a.Summary, tt.wantSummary)
+		}
+	}
+}
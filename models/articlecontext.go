@@ -0,0 +1,199 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Nutrients a user can be short of, or over.
+const (
+	GapProtein     = "protein_low"
+	GapFiber       = "fiber_low"
+	GapIron        = "iron_low"
+	GapCaloriesLow = "calories_low"
+	GapCalories    = "calories_high"
+)
+
+// Daily reference intakes for adult women, used when the plan sets none.
+const (
+	referenceProtein = 50.0
+	referenceFiber   = 25.0
+	referenceIron    = 18.0
+)
+
+// Goals of a diet plan, judged from its calorie goal against the user's
+// calculated requirement.
+const (
+	PlanGoalLose     = "weight_loss"
+	PlanGoalGain     = "weight_gain"
+	PlanGoalMaintain = "maintain"
+)
+
+// symptomCycles is how many recent cycles symptoms are taken from.
+const symptomCycles = 2
+
+// NutrientGap is a nutrient the user's recent meals fall short of, or
+// exceed, compared to the target.
+type NutrientGap struct {
+	Gap     string  `json:"gap"`
+	Average float64 `json:"average"`
+	Target  float64 `json:"target"`
+}
+
+// ArticleContext is the state of a user that article recommendations are
+// based on.
+type ArticleContext struct {
+	CyclePhase string        `json:"cycle_phase"`
+	CycleDay   int           `json:"cycle_day"`
+	Symptoms   []string      `json:"symptoms"`
+	Gaps       []NutrientGap `json:"gaps"`
+	// PlanGoal is empty without an active diet plan.
+	PlanGoal    string   `json:"plan_goal,omitempty"`
+	CalorieGoal int      `json:"calorie_goal,omitempty"`
+	Diets       []string `json:"diets"`
+}
+
+// getRecentSymptoms returns the symptoms logged in the user's latest cycles.
+func getRecentSymptoms(db *sql.DB, userID string, day time.Time) ([]string, error) {
+	query := `SELECT DISTINCT st.SymptomsName FROM daily_log dl
+	          JOIN symptoms_type st ON st.SymptomsID = dl.SymptomsID
+	          WHERE dl.CycleID IN (
+	              SELECT CycleID FROM (
+	                  SELECT CycleID FROM cycle WHERE UserID = ? AND StartDate <= ? ORDER BY StartDate DESC LIMIT ?
+	              ) latest
+	          )
+	          ORDER BY st.SymptomsName`
+	rows, err := db.Query(query, userID, day.Format("2006-01-02"), symptomCycles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	symptoms := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		symptoms = append(symptoms, name)
+	}
+	return symptoms, rows.Err()
+}
+
+// An iron average is only estimated when at least minIronFoods logged
+// servings, and minIronCoverage of all of them, have iron data, since
+// food_nutrient is only partly filled.
+const (
+	minIronFoods    = 5
+	minIronCoverage = 0.5
+)
+
+// estimateDailyIron averages the iron of the servings with known iron and
+// scales it to every serving logged over days. It returns false when too
+// few servings have iron data to tell.
+func estimateDailyIron(days int, servings, knownServings, knownIron float64) (float64, bool) {
+	if days <= 0 || knownServings < minIronFoods || knownServings < minIronCoverage*servings {
+		return 0, false
+	}
+	return knownIron / knownServings * servings / float64(days), true
+}
+
+// getAverageIron returns the average daily iron, in mg, of the logged days
+// between two dates, estimated from the foods with iron data. It returns
+// false when too few foods have iron data.
+func getAverageIron(db *sql.DB, userID string, from, to time.Time) (float64, bool, error) {
+	var days int
+	var servings, knownServings, knownIron float64
+	query := `SELECT COUNT(DISTINCT dm.TrackID), COALESCE(SUM(COALESCE(q.Quantity, 1)), 0),
+	                 COALESCE(SUM(CASE WHEN fn.Iron IS NULL THEN 0 ELSE COALESCE(q.Quantity, 1) END), 0),
+	                 COALESCE(SUM(fn.Iron * COALESCE(q.Quantity, 1)), 0)
+	          FROM daily_meal dm
+	          JOIN meal_detail md ON md.TrackID = dm.TrackID
+	          LEFT JOIN meal_detail_quantity q ON q.TrackID = md.TrackID AND q.FoodID = md.FoodID
+	          LEFT JOIN food_nutrient fn ON fn.FoodID = md.FoodID
+	          WHERE dm.UserID = ? AND dm.MealDate BETWEEN ? AND ?`
+	err := db.QueryRow(query, userID, from.Format("2006-01-02"), to.Format("2006-01-02")).Scan(&days, &servings, &knownServings, &knownIron)
+	if err != nil {
+		return 0, false, err
+	}
+	iron, ok := estimateDailyIron(days, servings, knownServings, knownIron)
+	return iron, ok, nil
+}
+
+// getPlanGoal compares a plan's calorie goal with the user's latest
+// calculated requirement, within 10%.
+func getPlanGoal(db *sql.DB, userID string, calorieGoal int) (string, error) {
+	need, err := GetLatestCalorieRequirement(db, userID)
+	if err != nil || need <= 0 {
+		return PlanGoalMaintain, err
+	}
+	switch {
+	case float64(calorieGoal) < 0.9*need:
+		return PlanGoalLose, nil
+	case float64(calorieGoal) > 1.1*need:
+		return PlanGoalGain, nil
+	}
+	return PlanGoalMaintain, nil
+}
+
+// GetArticleContext gathers the user's cycle phase, recent symptoms,
+// nutrient gaps over the last week and active diet plan.
+func GetArticleContext(db *sql.DB, userID string, day time.Time) (*ArticleContext, error) {
+	profile, err := GetNutritionProfile(db, userID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &ArticleContext{
+		CyclePhase: profile.CyclePhase,
+		CycleDay:   profile.CycleDay,
+		Gaps:       []NutrientGap{},
+		Diets:      profile.Diets,
+	}
+	if ctx.Symptoms, err = getRecentSymptoms(db, userID, day); err != nil {
+		return nil, err
+	}
+
+	if profile.CalorieSource == "diet_plan" {
+		ctx.CalorieGoal = profile.CalorieTarget
+		if ctx.PlanGoal, err = getPlanGoal(db, userID, profile.CalorieTarget); err != nil {
+			return nil, err
+		}
+	}
+
+	recent := profile.RecentIntake
+	if recent.DaysLogged == 0 {
+		return ctx, nil
+	}
+
+	proteinTarget := referenceProtein
+	if profile.Targets != nil && profile.Targets.ProteinGoal > 0 {
+		proteinTarget = profile.Targets.ProteinGoal
+	}
+	if recent.AverageProtein < 0.8*proteinTarget {
+		ctx.Gaps = append(ctx.Gaps, NutrientGap{GapProtein, recent.AverageProtein, proteinTarget})
+	}
+	if recent.AverageFiber < 0.8*referenceFiber {
+		ctx.Gaps = append(ctx.Gaps, NutrientGap{GapFiber, recent.AverageFiber, referenceFiber})
+	}
+
+	from := day.AddDate(0, 0, -(recentDays - 1))
+	iron, known, err := getAverageIron(db, userID, from, day)
+	if err != nil {
+		return nil, err
+	}
+	if known && iron < 0.8*referenceIron {
+		ctx.Gaps = append(ctx.Gaps, NutrientGap{GapIron, formatFloat(iron, 1), referenceIron})
+	}
+
+	if target := float64(profile.CalorieTarget); target > 0 {
+		switch {
+		case recent.AverageCalories < 0.8*target:
+			ctx.Gaps = append(ctx.Gaps, NutrientGap{GapCaloriesLow, recent.AverageCalories, target})
+		case recent.AverageCalories > 1.1*target:
+			ctx.Gaps = append(ctx.Gaps, NutrientGap{GapCalories, recent.AverageCalories, target})
+		}
+	}
+
+	return ctx, nil
+}
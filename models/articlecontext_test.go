@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestEstimateDailyIron(t *testing.T) {
+	tests := []struct {
+		name                       string
+		days                       int
+		servings, known, knownIron float64
+		want                       float64
+		wantOK                     bool
+	}{
+		{name: "every serving known", days: 2, servings: 10, known: 10, knownIron: 30, want: 15, wantOK: true},
+		{name: "unknown servings are estimated", days: 2, servings: 10, known: 6, knownIron: 12, want: 10, wantOK: true},
+		{name: "too few known servings", days: 1, servings: 4, known: 4, knownIron: 2},
+		{name: "too little coverage", days: 3, servings: 20, known: 6, knownIron: 60},
+		{name: "nothing logged"},
+	}
+	for _, tt := range tests {
+		got, ok := estimateDailyIron(tt.days, tt.servings, tt.known, tt.knownIron)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: estimateDailyIron = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// Bookmark is an article a user saved, identified by its link. RemindAt is
+// when to remind the user to read it, nil for no reminder. UpdatedAt is
+// when it was last saved, which also sets the reminder.
+type Bookmark struct {
+	Link      string     `json:"link"`
+	Title     string     `json:"title"`
+	Snippet   string     `json:"snippet"`
+	RemindAt  *time.Time `json:"remind_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Read is set when the user has opened the article since it was last
+	// saved.
+	Read bool `json:"read"`
+}
+
+// ArticleRead is one visit of an article.
+type ArticleRead struct {
+	Link   string    `json:"link"`
+	Title  string    `json:"title"`
+	ReadAt time.Time `json:"read_at"`
+}
+
+// linkHash keys links, which are too long for a MySQL index.
+func linkHash(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatNullTime formats t in server time, like the other DATETIME columns.
+func formatNullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// SaveBookmark adds a bookmark, or updates it when the link is already
+// saved, and fills b in as stored. Saving again resets the reminder, so
+// earlier reads no longer settle it.
+func SaveBookmark(db *sql.DB, userID string, b *Bookmark) error {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	query := `INSERT INTO article_bookmark (UserID, LinkHash, Link, Title, Snippet, RemindAt, CreatedAt, UpdatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	          ON DUPLICATE KEY UPDATE Title = VALUES(Title), Snippet = VALUES(Snippet), RemindAt = VALUES(RemindAt), UpdatedAt = VALUES(UpdatedAt)`
+	_, err := db.Exec(query, userID, linkHash(b.Link), b.Link, b.Title, b.Snippet, formatNullTime(b.RemindAt), now, now)
+	if err != nil {
+		return err
+	}
+
+	saved, err := queryBookmarks(db, bookmarkQuery+" WHERE b.UserID = ? AND b.LinkHash = ?", userID, linkHash(b.Link))
+	if err != nil {
+		return err
+	}
+	if len(saved) == 0 {
+		return sql.ErrNoRows
+	}
+	*b = saved[0]
+	return nil
+}
+
+// DeleteBookmark removes a bookmark, reporting whether it existed.
+func DeleteBookmark(db *sql.DB, userID, link string) (bool, error) {
+	result, err := db.Exec("DELETE FROM article_bookmark WHERE UserID = ? AND LinkHash = ?", userID, linkHash(link))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// bookmarkQuery selects bookmarks with whether they were read after being
+// last saved.
+const bookmarkQuery = `SELECT b.Link, b.Title, b.Snippet, b.RemindAt, b.CreatedAt, b.UpdatedAt,
+	EXISTS (SELECT 1 FROM article_read r WHERE r.UserID = b.UserID AND r.LinkHash = b.LinkHash AND r.ReadAt >= b.UpdatedAt)
+	FROM article_bookmark b`
+
+func queryBookmarks(db *sql.DB, query string, args ...interface{}) ([]Bookmark, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookmarks := []Bookmark{}
+	for rows.Next() {
+		var b Bookmark
+		var remindAt sql.NullString
+		var createdAt, updatedAt string
+		if err := rows.Scan(&b.Link, &b.Title, &b.Snippet, &remindAt, &createdAt, &updatedAt, &b.Read); err != nil {
+			return nil, err
+		}
+		if b.CreatedAt, err = parseDBDate(createdAt); err != nil {
+			return nil, err
+		}
+		if b.UpdatedAt, err = parseDBDate(updatedAt); err != nil {
+			return nil, err
+		}
+		if remindAt.Valid {
+			t, err := parseDBDate(remindAt.String)
+			if err != nil {
+				return nil, err
+			}
+			b.RemindAt = &t
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// ListBookmarks returns a user's bookmarks, newest first.
+func ListBookmarks(db *sql.DB, userID string) ([]Bookmark, error) {
+	return queryBookmarks(db, bookmarkQuery+" WHERE b.UserID = ? ORDER BY b.CreatedAt DESC", userID)
+}
+
+// DueReminders returns the bookmarks whose reminder time has passed and
+// which have not been read since they were last saved.
+func DueReminders(db *sql.DB, userID string, now time.Time) ([]Bookmark, error) {
+	query := bookmarkQuery + ` WHERE b.UserID = ? AND b.RemindAt <= ?
+	    AND NOT EXISTS (SELECT 1 FROM article_read r WHERE r.UserID = b.UserID AND r.LinkHash = b.LinkHash AND r.ReadAt >= b.UpdatedAt)
+	    ORDER BY b.RemindAt`
+	return queryBookmarks(db, query, userID, now.Format("2006-01-02 15:04:05"))
+}
+
+// RecordArticleRead adds a visit to the user's reading history.
+func RecordArticleRead(db *sql.DB, userID string, read *ArticleRead) error {
+	read.ReadAt = time.Now()
+	_, err := db.Exec("INSERT INTO article_read (UserID, LinkHash, Link, Title, ReadAt) VALUES (?, ?, ?, ?, ?)",
+		userID, linkHash(read.Link), read.Link, read.Title, read.ReadAt.Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetReadingHistory returns the most recent visits of a user.
+func GetReadingHistory(db *sql.DB, userID string, limit int) ([]ArticleRead, error) {
+	rows, err := db.Query("SELECT Link, Title, ReadAt FROM article_read WHERE UserID = ? ORDER BY ReadAt DESC, ReadID DESC LIMIT ?", userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []ArticleRead{}
+	for rows.Next() {
+		var read ArticleRead
+		var readAt string
+		if err := rows.Scan(&read.Link, &read.Title, &readAt); err != nil {
+			return nil, err
+		}
+		if read.ReadAt, err = parseDBDate(readAt); err != nil {
+			return nil, err
+		}
+		history = append(history, read)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Ways a free-text dish can relate to the food catalogue.
+const (
+	MatchFound       = "matched"
+	MatchSubstituted = "substituted"
+	MatchUnknown     = "unknown"
+)
+
+// Thresholds used by MatchFood. A dish is matched on a close name alone; a
+// weaker name match is accepted as a substitute when calories also agree.
+const (
+	matchNameThreshold      = 0.7
+	substituteNameThreshold = 0.4
+	substituteCalorieRatio  = 0.85
+)
+
+// CatalogueFood is a food from the catalogue with every name it is known by.
+type CatalogueFood struct {
+	FoodID   string
+	Name     string
+	Names    []string
+	Serving  int
+	Calories int
+	Tags     []string
+	// Price is the price per serving in rupiah, nil when unknown.
+	Price *int
+}
+
+// FoodMatch is the result of matching a free-text dish to the catalogue.
+type FoodMatch struct {
+	Food  *CatalogueFood
+	Kind  string
+	Score float64
+}
+
+// GetFoodCatalogue loads every food with its translated names and tags.
+func GetFoodCatalogue(db *sql.DB) ([]CatalogueFood, error) {
+	rows, err := db.Query("SELECT f.FoodID, f.Name, f.Serving, f.Calories, fp.PricePerServing FROM food f LEFT JOIN food_price fp ON fp.FoodID = f.FoodID ORDER BY f.Name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foods []CatalogueFood
+	index := make(map[string]int)
+	for rows.Next() {
+		var food CatalogueFood
+		if err := rows.Scan(&food.FoodID, &food.Name, &food.Serving, &food.Calories, &food.Price); err != nil {
+			return nil, err
+		}
+		food.Names = []string{food.Name}
+		index[food.FoodID] = len(foods)
+		foods = append(foods, food)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nameRows, err := db.Query("SELECT FoodID, Name FROM food_translation")
+	if err != nil {
+		return nil, err
+	}
+	defer nameRows.Close()
+
+	for nameRows.Next() {
+		var foodID, name string
+		if err := nameRows.Scan(&foodID, &name); err != nil {
+			return nil, err
+		}
+		if i, ok := index[foodID]; ok {
+			foods[i].Names = append(foods[i].Names, name)
+		}
+	}
+	if err := nameRows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := GetAllFoodTags(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range foods {
+		foods[i].Tags = tags[foods[i].FoodID]
+	}
+
+	return foods, nil
+}
+
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func bigrams(s string) map[string]int {
+	grams := make(map[string]int)
+	runes := []rune(s)
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	return grams
+}
+
+// NameSimilarity is the Sørensen–Dice coefficient of the character bigrams
+// of both names, from 0 (nothing in common) to 1 (same name).
+func NameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ga, gb := bigrams(a), bigrams(b)
+	total := 0
+	for _, n := range ga {
+		total += n
+	}
+	for _, n := range gb {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	shared := 0
+	for g, n := range ga {
+		if m, ok := gb[g]; ok {
+			shared += int(math.Min(float64(n), float64(m)))
+		}
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// calorieCloseness is 1 for equal calories and drops to 0 as they diverge.
+func calorieCloseness(a, b int) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	diff := math.Abs(float64(a - b))
+	return 1 - math.Min(diff/math.Max(float64(a), float64(b)), 1)
+}
+
+// MatchFood finds the catalogue food that best fits a dish name and its
+// calories. calories may be zero when unknown.
+func MatchFood(catalogue []CatalogueFood, name string, calories int) FoodMatch {
+	best := FoodMatch{Kind: MatchUnknown}
+	bestSubstitute := FoodMatch{Kind: MatchUnknown}
+
+	for i := range catalogue {
+		food := &catalogue[i]
+
+		nameScore := 0.0
+		for _, n := range food.Names {
+			nameScore = math.Max(nameScore, NameSimilarity(name, n))
+		}
+
+		score := nameScore
+		closeness := calorieCloseness(calories, food.Calories)
+		if calories > 0 {
+			score = 0.75*nameScore + 0.25*closeness
+		}
+
+		if nameScore >= matchNameThreshold && score > best.Score {
+			best = FoodMatch{Food: food, Kind: MatchFound, Score: score}
+		}
+		if nameScore >= substituteNameThreshold && closeness >= substituteCalorieRatio && score > bestSubstitute.Score {
+			bestSubstitute = FoodMatch{Food: food, Kind: MatchSubstituted, Score: score}
+		}
+	}
+
+	if best.Food != nil {
+		best.Score = formatFloat(best.Score, 2)
+		return best
+	}
+	bestSubstitute.Score = formatFloat(bestSubstitute.Score, 2)
+	return bestSubstitute
+}
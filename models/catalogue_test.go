@@ -0,0 +1,57 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Nasi Goreng", "nasi goreng!", 1},
+		{"abc", "abd", 0.5},
+		{"night", "nacht", 0.25},
+		{"soto sapi", "Soto Ayam", 0.5},
+		{"", "nasi", 0},
+		{"a", "b", 0},
+	}
+	for _, tt := range tests {
+		if got := NameSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("NameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchFood(t *testing.T) {
+	catalogue := []CatalogueFood{
+		{FoodID: "FD001", Name: "Nasi Goreng", Names: []string{"Nasi Goreng", "Fried Rice"}, Calories: 350},
+		{FoodID: "FD002", Name: "Soto Ayam", Names: []string{"Soto Ayam"}, Calories: 300},
+		{FoodID: "FD003", Name: "Tempe Goreng", Names: []string{"Tempe Goreng"}, Calories: 160},
+	}
+	tests := []struct {
+		name      string
+		calories  int
+		wantKind  string
+		wantFood  string
+		wantScore float64
+	}{
+		{"Fried Rice", 0, MatchFound, "FD001", 1},
+		{"nasi goreng", 350, MatchFound, "FD001", 1},
+		{"Nasi Goreng", 175, MatchFound, "FD001", 0.88},
+		{"Soto Sapi", 290, MatchSubstituted, "FD002", 0.62},
+		{"Soto Sapi", 150, MatchUnknown, "", 0},
+		{"Pizza", 0, MatchUnknown, "", 0},
+	}
+	for _, tt := range tests {
+		got := MatchFood(catalogue, tt.name, tt.calories)
+		foodID := ""
+		if got.Food != nil {
+			foodID = got.Food.FoodID
+		}
+		if got.Kind != tt.wantKind || foodID != tt.wantFood || got.Score != tt.wantScore {
+			t.Errorf("MatchFood(%q, %d) = %s %s %v, want %s %s %v", tt.name, tt.calories, got.Kind, foodID, got.Score, tt.wantKind, tt.wantFood, tt.wantScore)
+		}
+	}
+}
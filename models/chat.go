@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrThreadNotFound is returned when a chat thread does not exist.
+var ErrThreadNotFound = errors.New("chat thread not found")
+
+// ChatThread is a conversation of a user with the nutrition assistant.
+type ChatThread struct {
+	ThreadID  int       `json:"thread_id"`
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChatMessage is one turn of a thread, written by the user or the model.
+type ChatMessage struct {
+	MessageID int       `json:"message_id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func createChatThread(tx *sql.Tx, thread *ChatThread) error {
+	thread.CreatedAt = time.Now()
+	thread.UpdatedAt = thread.CreatedAt
+	now := thread.CreatedAt.Format("2006-01-02 15:04:05")
+
+	result, err := tx.Exec("INSERT INTO chat_thread (UserID, Title, CreatedAt, UpdatedAt) VALUES (?, ?, ?, ?)", thread.UserID, thread.Title, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	thread.ThreadID = int(id)
+	return nil
+}
+
+func scanChatThread(row interface{ Scan(...interface{}) error }) (*ChatThread, error) {
+	var thread ChatThread
+	var createdAt, updatedAt string
+	err := row.Scan(&thread.ThreadID, &thread.UserID, &thread.Title, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if thread.CreatedAt, err = parseDBDate(createdAt); err != nil {
+		return nil, err
+	}
+	if thread.UpdatedAt, err = parseDBDate(updatedAt); err != nil {
+		return nil, err
+	}
+	return &thread, nil
+}
+
+// GetChatThread returns a thread by ID, or ErrThreadNotFound.
+func GetChatThread(db *sql.DB, threadID int) (*ChatThread, error) {
+	row := db.QueryRow("SELECT ThreadID, UserID, Title, CreatedAt, UpdatedAt FROM chat_thread WHERE ThreadID = ?", threadID)
+	thread, err := scanChatThread(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrThreadNotFound
+	}
+	return thread, err
+}
+
+// ListChatThreads returns the threads of a user, most recently active first.
+func ListChatThreads(db *sql.DB, userID string) ([]ChatThread, error) {
+	rows, err := db.Query("SELECT ThreadID, UserID, Title, CreatedAt, UpdatedAt FROM chat_thread WHERE UserID = ? ORDER BY UpdatedAt DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	threads := []ChatThread{}
+	for rows.Next() {
+		thread, err := scanChatThread(rows)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, *thread)
+	}
+	return threads, rows.Err()
+}
+
+// GetChatMessages returns the messages of a thread, oldest first.
+func GetChatMessages(db *sql.DB, threadID int) ([]ChatMessage, error) {
+	rows, err := db.Query("SELECT MessageID, Role, Content, CreatedAt FROM chat_message WHERE ThreadID = ? ORDER BY MessageID", threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []ChatMessage{}
+	for rows.Next() {
+		var msg ChatMessage
+		var createdAt string
+		if err := rows.Scan(&msg.MessageID, &msg.Role, &msg.Content, &createdAt); err != nil {
+			return nil, err
+		}
+		if msg.CreatedAt, err = parseDBDate(createdAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// AddChatMessages appends messages to a thread and marks it as active. A
+// thread without an ID is created first, in the same transaction, so a
+// thread never exists without its messages.
+func AddChatMessages(db *sql.DB, thread *ChatThread, messages ...ChatMessage) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if thread.ThreadID == 0 {
+		if err := createChatThread(tx, thread); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	for _, msg := range messages {
+		_, err = tx.Exec("INSERT INTO chat_message (ThreadID, Role, Content, CreatedAt) VALUES (?, ?, ?, ?)", thread.ThreadID, msg.Role, msg.Content, now)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE chat_thread SET UpdatedAt = ? WHERE ThreadID = ?", now, thread.ThreadID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func DeleteChatThread(db *sql.DB, threadID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM chat_message WHERE ThreadID = ?", threadID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM chat_thread WHERE ThreadID = ?", threadID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -138,41 +139,113 @@ func GetUserByEmail(db *sql.DB, email string) (*User, error) {
 	return &user, nil
 }
 
+func GetUserByID(db *sql.DB, userID string) (*User, error) {
+	query := "SELECT UserID, Name, Username, Email, Passwords, Birthdate, Height, Weight FROM users WHERE UserID = ?"
+	row := db.QueryRow(query, userID)
+
+	var user User
+	var birthdateStr string
+	err := row.Scan(&user.UserID, &user.Name, &user.Username, &user.Email, &user.Passwords, &birthdateStr, &user.Height, &user.Weight)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Pengguna tidak ditemukan
+		}
+		return nil, err
+	}
+
+	user.Birthdate, err = parseDBDate(birthdateStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 func GenerateSequentialTrackID() (string, error) {
-	db := GetDB()
+	return generateSequentialID(GetDB(), "daily_meal", "TrackID", "TR")
+}
+
+// ErrIDsExhausted is returned when every ID of a prefix is taken. IDs are
+// char(5), so a two letter prefix leaves room for 999 rows.
+var ErrIDsExhausted = errors.New("no free IDs left")
 
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// generateSequentialID returns the next ID with the given two letter prefix
+// by incrementing the highest existing one. table and column are never user
+// input. Inside a transaction the highest row stays locked until commit.
+func generateSequentialID(db rowQuerier, table, column, prefix string) (string, error) {
 	var maxID sql.NullString
-	query := "SELECT MAX(TrackID) FROM daily_meal"
-	err := db.QueryRow(query).Scan(&maxID)
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s WHERE %s LIKE ? FOR UPDATE", column, table, column)
+	err := db.QueryRow(query, prefix+"%").Scan(&maxID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			// No rows in table, start with TR0001
-			return "TR001", nil
+			// No rows in table, start with 001
+			return prefix + "001", nil
 		}
 		return "", err
 	}
 
 	if !maxID.Valid || maxID.String == "" {
-		return "TR001", nil
+		return prefix + "001", nil
 	}
 
 	// Extract the numeric part and increment it
-	numericPart := maxID.String[2:] // Remove "TR" prefix
+	numericPart := maxID.String[len(prefix):]
 	number, err := strconv.Atoi(numericPart)
 	if err != nil {
 		return "", err
 	}
 
 	nextNumber := number + 1
-	nextID := fmt.Sprintf("TR%03d", nextNumber)
+	if nextNumber > 999 {
+		return "", fmt.Errorf("%w for %s", ErrIDsExhausted, table)
+	}
+	nextID := fmt.Sprintf("%s%03d", prefix, nextNumber)
 
 	return nextID, nil
 }
 
+// SaveCalorieData stores a calculated requirement. users_calorie has no
+// order, so the calculation is also logged in calorie_requirement for
+// GetLatestCalorieRequirement.
 func SaveCalorieData(db *sql.DB, user_id string, age int, height, weight float64, activity string, calories float64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
 	query := `INSERT INTO users_calorie (UserID, Age, Height, Weight, Activity, Calories) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := db.Exec(query, user_id, age, height, weight, activity, calories)
-	return err
+	if _, err := tx.Exec(query, user_id, age, height, weight, activity, calories); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = "INSERT INTO calorie_requirement (UserID, Calories, CalculatedAt) VALUES (?, ?, ?)"
+	if _, err := tx.Exec(query, user_id, calories, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetLatestCalorieRequirement returns the user's most recently calculated
+// daily calorie requirement, or 0 when there is none. Users whose only
+// calculations predate calorie_requirement fall back to users_calorie,
+// which cannot tell which of several rows is the latest.
+func GetLatestCalorieRequirement(db *sql.DB, userID string) (float64, error) {
+	var calories float64
+	err := db.QueryRow("SELECT Calories FROM calorie_requirement WHERE UserID = ? ORDER BY RequirementID DESC LIMIT 1", userID).Scan(&calories)
+	if err == sql.ErrNoRows {
+		err = db.QueryRow("SELECT Calories FROM users_calorie WHERE UserID = ? LIMIT 1", userID).Scan(&calories)
+	}
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return formatFloat(calories, 2), err
 }
 
 func formatFloat(num float64, precision int) float64 {
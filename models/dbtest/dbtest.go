@@ -0,0 +1,156 @@
+// Package dbtest is an in-memory database/sql driver for handler tests.
+// Queries are answered by a Responder instead of a database, and every
+// statement is recorded so tests can check what was written.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Responder returns the rows for a query, or an error to fail a query or
+// statement. Returning no rows makes QueryRow report sql.ErrNoRows; rows
+// returned for Exec are ignored.
+type Responder func(query string, args []driver.Value) ([][]driver.Value, error)
+
+// DB records the statements run against it.
+type DB struct {
+	respond Responder
+
+	mu         sync.Mutex
+	statements []string
+}
+
+// Open returns a database answered by respond, which may be nil to answer
+// every query with no rows. A successful Exec affects one row and inserts
+// ID 1. Transactions are recorded as BEGIN, COMMIT and ROLLBACK.
+func Open(respond Responder) (*sql.DB, *DB) {
+	d := &DB{respond: respond}
+	return sql.OpenDB(d), d
+}
+
+// Statements returns the statements run so far, in order.
+func (d *DB) Statements() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string{}, d.statements...)
+}
+
+// Ran reports whether a statement starting with prefix was run.
+func (d *DB) Ran(prefix string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, s := range d.statements {
+		if strings.HasPrefix(strings.TrimSpace(s), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DB) record(query string) {
+	d.mu.Lock()
+	d.statements = append(d.statements, query)
+	d.mu.Unlock()
+}
+
+// DB is its own driver.Connector and driver.Driver.
+
+func (d *DB) Connect(ctx context.Context) (driver.Conn, error) { return &conn{db: d}, nil }
+func (d *DB) Driver() driver.Driver                            { return d }
+func (d *DB) Open(name string) (driver.Conn, error)            { return &conn{db: d}, nil }
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{db: c.db, query: query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN")
+	return tx{db: c.db}, nil
+}
+
+type tx struct {
+	db *DB
+}
+
+func (t tx) Commit() error {
+	t.db.record("COMMIT")
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.db.record("ROLLBACK")
+	return nil
+}
+
+type stmt struct {
+	db    *DB
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query)
+	if s.db.respond != nil {
+		if _, err := s.db.respond(s.query, args); err != nil {
+			return nil, err
+		}
+	}
+	return result{}, nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query)
+	if s.db.respond == nil {
+		return &rows{}, nil
+	}
+	values, err := s.db.respond(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{values: values}, nil
+}
+
+type result struct{}
+
+func (result) LastInsertId() (int64, error) { return 1, nil }
+func (result) RowsAffected() (int64, error) { return 1, nil }
+
+type rows struct {
+	values [][]driver.Value
+	next   int
+}
+
+// Columns are named by position; the models scan by position only.
+func (r *rows) Columns() []string {
+	if len(r.values) == 0 {
+		return nil
+	}
+	columns := make([]string, len(r.values[0]))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+	}
+	return columns
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
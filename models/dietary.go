@@ -0,0 +1,323 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Allergen tags mark foods that contain the allergen.
+const (
+	TagGluten    = "gluten"
+	TagDairy     = "dairy"
+	TagNuts      = "nuts"
+	TagShellfish = "shellfish"
+)
+
+// Diet tags mark foods that are suitable for the diet.
+const (
+	TagVegan      = "vegan"
+	TagVegetarian = "vegetarian"
+	TagHalal      = "halal"
+)
+
+// Preference kinds stored in user_preference.
+const (
+	PreferenceAllergy = "allergy"
+	PreferenceDiet    = "diet"
+)
+
+var allergenTags = map[string]bool{TagGluten: true, TagDairy: true, TagNuts: true, TagShellfish: true}
+
+var dietTags = map[string]bool{TagVegan: true, TagVegetarian: true, TagHalal: true}
+
+// AllergenKeywords maps allergen tags to words (English and Indonesian) that
+// usually indicate the allergen in a free-text dish name.
+var AllergenKeywords = map[string][]string{
+	TagGluten:    {"wheat", "bread", "pasta", "noodle", "flour", "roti", "mie", "terigu", "gandum"},
+	TagDairy:     {"milk", "cheese", "butter", "yogurt", "cream", "susu", "keju", "mentega"},
+	TagNuts:      {"peanut", "almond", "cashew", "walnut", "kacang", "mete"},
+	TagShellfish: {"shrimp", "prawn", "crab", "lobster", "clam", "mussel", "oyster", "udang", "kepiting", "kerang", "cumi"},
+}
+
+// UserPreferences holds the allergies, diets and disliked foods chosen by
+// a user.
+type UserPreferences struct {
+	UserID    string   `json:"user_id"`
+	Allergies []string `json:"allergies"`
+	Diets     []string `json:"diets"`
+	Dislikes  []string `json:"dislikes"`
+}
+
+// TagConflict describes why a food does not fit a user's preferences.
+type TagConflict struct {
+	FoodID  string `json:"food_id,omitempty"`
+	Kind    string `json:"kind"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+}
+
+func normalizeTags(tags []string, valid map[string]bool) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !valid[tag] {
+			return nil, fmt.Errorf("invalid tag: %s", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// NormalizeFoodTags lowercases, deduplicates and validates food tags.
+func NormalizeFoodTags(tags []string) ([]string, error) {
+	valid := make(map[string]bool)
+	for tag := range allergenTags {
+		valid[tag] = true
+	}
+	for tag := range dietTags {
+		valid[tag] = true
+	}
+	return normalizeTags(tags, valid)
+}
+
+func SetFoodTags(db *sql.DB, foodID string, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := setFoodTags(tx, foodID, tags); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func setFoodTags(tx *sql.Tx, foodID string, tags []string) error {
+	_, err := tx.Exec("DELETE FROM food_tag WHERE FoodID = ?", foodID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tx.Exec("INSERT INTO food_tag (FoodID, Tag) VALUES (?, ?)", foodID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFoodTags returns the tags of a single food.
+func GetFoodTags(db *sql.DB, foodID string) ([]string, error) {
+	rows, err := db.Query("SELECT Tag FROM food_tag WHERE FoodID = ? ORDER BY Tag", foodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetAllFoodTags returns the tags of every tagged food, keyed by FoodID.
+func GetAllFoodTags(db *sql.DB) (map[string][]string, error) {
+	rows, err := db.Query("SELECT FoodID, Tag FROM food_tag ORDER BY FoodID, Tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var foodID, tag string
+		if err := rows.Scan(&foodID, &tag); err != nil {
+			return nil, err
+		}
+		tags[foodID] = append(tags[foodID], tag)
+	}
+	return tags, rows.Err()
+}
+
+func GetUserPreferences(db *sql.DB, userID string) (*UserPreferences, error) {
+	rows, err := db.Query("SELECT Kind, Tag FROM user_preference WHERE UserID = ? ORDER BY Kind, Tag", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := &UserPreferences{UserID: userID, Allergies: []string{}, Diets: []string{}, Dislikes: []string{}}
+	for rows.Next() {
+		var kind, tag string
+		if err := rows.Scan(&kind, &tag); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case PreferenceAllergy:
+			prefs.Allergies = append(prefs.Allergies, tag)
+		case PreferenceDiet:
+			prefs.Diets = append(prefs.Diets, tag)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dislikeRows, err := db.Query("SELECT Name FROM user_disliked_food WHERE UserID = ? ORDER BY Name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer dislikeRows.Close()
+
+	for dislikeRows.Next() {
+		var name string
+		if err := dislikeRows.Scan(&name); err != nil {
+			return nil, err
+		}
+		prefs.Dislikes = append(prefs.Dislikes, name)
+	}
+	return prefs, dislikeRows.Err()
+}
+
+// SaveUserPreferences validates and replaces all preferences of a user.
+func SaveUserPreferences(db *sql.DB, prefs *UserPreferences) error {
+	allergies, err := normalizeTags(prefs.Allergies, allergenTags)
+	if err != nil {
+		return err
+	}
+	diets, err := normalizeTags(prefs.Diets, dietTags)
+	if err != nil {
+		return err
+	}
+	prefs.Allergies = allergies
+	prefs.Diets = diets
+
+	dislikes := []string{}
+	seen := make(map[string]bool)
+	for _, name := range prefs.Dislikes {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			dislikes = append(dislikes, name)
+		}
+	}
+	prefs.Dislikes = dislikes
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM user_preference WHERE UserID = ?", prefs.UserID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, tag := range allergies {
+		_, err = tx.Exec("INSERT INTO user_preference (UserID, Kind, Tag) VALUES (?, ?, ?)", prefs.UserID, PreferenceAllergy, tag)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, tag := range diets {
+		_, err = tx.Exec("INSERT INTO user_preference (UserID, Kind, Tag) VALUES (?, ?, ?)", prefs.UserID, PreferenceDiet, tag)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM user_disliked_food WHERE UserID = ?", prefs.UserID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, name := range dislikes {
+		_, err = tx.Exec("INSERT INTO user_disliked_food (UserID, Name) VALUES (?, ?)", prefs.UserID, name)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FoodConflictsFor is FoodConflicts with the conflicts labelled by FoodID.
+func FoodConflictsFor(foodID string, foodTags []string, prefs *UserPreferences) []TagConflict {
+	conflicts := FoodConflicts(foodTags, prefs)
+	for i := range conflicts {
+		conflicts[i].FoodID = foodID
+	}
+	return conflicts
+}
+
+// FoodConflicts compares the tags of a food with the user's preferences.
+// A food conflicts with an allergy when it carries the allergen tag, and
+// with a diet when it is not tagged as suitable for it.
+func FoodConflicts(foodTags []string, prefs *UserPreferences) []TagConflict {
+	has := make(map[string]bool)
+	for _, tag := range foodTags {
+		has[tag] = true
+	}
+	// Vegan food is always vegetarian as well.
+	if has[TagVegan] {
+		has[TagVegetarian] = true
+	}
+
+	conflicts := []TagConflict{}
+	for _, tag := range prefs.Allergies {
+		if has[tag] {
+			conflicts = append(conflicts, TagConflict{Kind: PreferenceAllergy, Tag: tag, Message: "Food contains " + tag})
+		}
+	}
+	for _, tag := range prefs.Diets {
+		if !has[tag] {
+			conflicts = append(conflicts, TagConflict{Kind: PreferenceDiet, Tag: tag, Message: "Food is not marked as " + tag})
+		}
+	}
+	return conflicts
+}
+
+// HasAllergyConflict reports whether any conflict comes from an allergy.
+func HasAllergyConflict(conflicts []TagConflict) bool {
+	for _, c := range conflicts {
+		if c.Kind == PreferenceAllergy {
+			return true
+		}
+	}
+	return false
+}
+
+// TextConflicts looks for allergen keywords in free text such as an AI
+// generated meal plan, returning one conflict per matched allergy.
+func TextConflicts(text string, prefs *UserPreferences) []TagConflict {
+	lower := strings.ToLower(text)
+	conflicts := []TagConflict{}
+	for _, tag := range prefs.Allergies {
+		for _, keyword := range AllergenKeywords[tag] {
+			if strings.Contains(lower, keyword) {
+				conflicts = append(conflicts, TagConflict{Kind: PreferenceAllergy, Tag: tag, Message: "Mentions " + keyword + " which may contain " + tag})
+				break
+			}
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidFood is returned when a logged food is not in the catalogue.
+var ErrInvalidFood = errors.New("invalid food_id")
+
+// LoggedFood is a catalogue food eaten in a number of servings.
+type LoggedFood struct {
+	FoodID   string  `json:"food_id"`
+	Servings float64 `json:"servings"`
+}
+
+// CheckFoodConflicts returns the conflicts between the foods' tags and the
+// allergies and diets stored for the user.
+func CheckFoodConflicts(db *sql.DB, userID string, foodIDs []string) ([]TagConflict, error) {
+	prefs, err := GetUserPreferences(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := []TagConflict{}
+	seen := make(map[string]bool)
+	for _, foodID := range foodIDs {
+		if seen[foodID] {
+			continue
+		}
+		seen[foodID] = true
+		tags, err := GetFoodTags(db, foodID)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, FoodConflictsFor(foodID, tags, prefs)...)
+	}
+
+	return conflicts, nil
+}
+
+// LogMealFoods adds foods to the user's daily_meal for mealDate, creating
+// the day when needed, and updates the day's total calories. This is the one
+// logging path used by every endpoint that records eaten food.
+//
+// meal_detail holds one row per food and day, so logging a food again, or
+// listing it twice, adds its servings to its meal_detail_quantity instead.
+func LogMealFoods(db *sql.DB, userID string, mealDate time.Time, foods []LoggedFood) error {
+	// Start transaction to ensure atomicity
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = logMealFoods(tx, userID, mealDate, foods)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func logMealFoods(tx *sql.Tx, userID string, mealDate time.Time, foods []LoggedFood) error {
+	date := mealDate.Format("2006-01-02")
+
+	// Check if daily meal already exists for the user and meal date
+	var trackID string
+	err := tx.QueryRow("SELECT TrackID FROM daily_meal WHERE UserID = ? AND MealDate = ?", userID, date).Scan(&trackID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check existing daily meal: %v", err)
+	}
+
+	if trackID == "" {
+		trackID, err = GenerateSequentialTrackID()
+		if err != nil {
+			return fmt.Errorf("failed to generate TrackID: %v", err)
+		}
+
+		_, err = tx.Exec("INSERT INTO daily_meal (TrackID, UserID, MealDate, TotalCalories) VALUES (?, ?, ?, 0)", trackID, userID, date)
+		if err != nil {
+			return fmt.Errorf("failed to create daily meal: %v", err)
+		}
+	}
+
+	for _, food := range foods {
+		// Check if food_id exists in the food table
+		var foodExists bool
+		err = tx.QueryRow("SELECT COUNT(*) > 0 FROM food WHERE FoodID = ?", food.FoodID).Scan(&foodExists)
+		if err != nil {
+			return err
+		}
+		if !foodExists {
+			return fmt.Errorf("%w: %s", ErrInvalidFood, food.FoodID)
+		}
+
+		if err := addServing(tx, trackID, food.FoodID, food.Servings); err != nil {
+			return err
+		}
+	}
+
+	// Calculate and store the updated total calories
+	var newTotalCalories int
+	query := `SELECT COALESCE(ROUND(SUM(f.Calories * COALESCE(q.Quantity, 1))), 0) FROM meal_detail md
+	          JOIN food f ON md.FoodID = f.FoodID
+	          LEFT JOIN meal_detail_quantity q ON q.TrackID = md.TrackID AND q.FoodID = md.FoodID
+	          WHERE md.TrackID = ?`
+	err = tx.QueryRow(query, trackID).Scan(&newTotalCalories)
+	if err != nil {
+		return fmt.Errorf("failed to calculate total calories: %v", err)
+	}
+
+	_, err = tx.Exec("UPDATE daily_meal SET TotalCalories = ? WHERE TrackID = ?", newTotalCalories, trackID)
+	if err != nil {
+		return fmt.Errorf("failed to update total calories: %v", err)
+	}
+
+	return nil
+}
+
+// addServing records servings of a food in a day. Rows of meal_detail
+// without a quantity were logged before quantities existed and count as one
+// serving.
+func addServing(tx *sql.Tx, trackID, foodID string, servings float64) error {
+	var logged bool
+	err := tx.QueryRow("SELECT COUNT(*) > 0 FROM meal_detail WHERE TrackID = ? AND FoodID = ? FOR UPDATE", trackID, foodID).Scan(&logged)
+	if err != nil {
+		return fmt.Errorf("failed to check meal detail: %v", err)
+	}
+
+	if !logged {
+		_, err = tx.Exec("INSERT INTO meal_detail (TrackID, FoodID) VALUES (?, ?)", trackID, foodID)
+		if err != nil {
+			return fmt.Errorf("failed to add meal detail: %v", err)
+		}
+		_, err = tx.Exec("REPLACE INTO meal_detail_quantity (TrackID, FoodID, Quantity) VALUES (?, ?, ?)", trackID, foodID, servings)
+		if err != nil {
+			return fmt.Errorf("failed to add meal quantity: %v", err)
+		}
+		return nil
+	}
+
+	_, err = tx.Exec("INSERT INTO meal_detail_quantity (TrackID, FoodID, Quantity) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE Quantity = Quantity + ?", trackID, foodID, 1+servings, servings)
+	if err != nil {
+		return fmt.Errorf("failed to update meal quantity: %v", err)
+	}
+	return nil
+}
@@ -1,20 +1,219 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 	"nutrishe/entity"
+	"time"
 )
 
-func GetDayAndGoal(userID string) entity.DietPlan {
-	db := GetDB()
-	err := db.QueryRow("SELECT * FROM diet_plan WHERE UserID = ?", userID)
+// Diet plan statuses, relative to the current day.
+const (
+	PlanUpcoming = "upcoming"
+	PlanActive   = "active"
+	PlanPast     = "past"
+)
+
+// ErrPlanOverlap is returned when a plan would overlap another plan of the
+// same user. A user has at most one plan on any given day.
+var ErrPlanOverlap = errors.New("diet plan overlaps an existing plan")
 
+// ErrPlanNotFound is returned when a plan does not exist.
+var ErrPlanNotFound = errors.New("diet plan not found")
+
+// Status reports whether the plan is upcoming, active or past on day.
+func (p DietPlan) Status(day time.Time) string {
+	day = truncateDay(day)
+	switch {
+	case day.Before(truncateDay(p.StartDate)):
+		return PlanUpcoming
+	case day.After(truncateDay(p.EndDate)):
+		return PlanPast
+	default:
+		return PlanActive
+	}
+}
+
+func GetDayAndGoal(userID string) entity.DietPlan {
 	var temp entity.DietPlan
-	err.Scan(&temp.PlanID, &temp.UserID, &temp.StartDate, &temp.EndDate, &temp.CalorieGoal)
 
+	plan, err := GetCurrentDietPlan(GetDB(), userID, time.Now())
 	if err != nil {
-		log.Println("waduh")
+		log.Println("waduh", err)
+		return temp
+	}
+	if plan == nil {
+		return temp
 	}
 
+	temp.PlanID = plan.PlanID
+	temp.UserID = plan.UserID
+	temp.StartDate = plan.StartDate.Format("2006-01-02")
+	temp.EndDate = plan.EndDate.Format("2006-01-02")
+	temp.CalorieGoal = plan.CalorieGoal
 	return temp
 }
+
+func scanDietPlan(row interface{ Scan(...interface{}) error }) (*DietPlan, error) {
+	var plan DietPlan
+	var start, end string
+	err := row.Scan(&plan.PlanID, &plan.UserID, &start, &end, &plan.CalorieGoal)
+	if err != nil {
+		return nil, err
+	}
+	if plan.StartDate, err = parseDBDate(start); err != nil {
+		return nil, err
+	}
+	if plan.EndDate, err = parseDBDate(end); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetDietPlan returns a plan by ID, or ErrPlanNotFound.
+func GetDietPlan(db *sql.DB, planID string) (*DietPlan, error) {
+	row := db.QueryRow("SELECT PlanID, UserID, StartDate, EndDate, CalorieGoal FROM diet_plan WHERE PlanID = ?", planID)
+	plan, err := scanDietPlan(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrPlanNotFound
+	}
+	return plan, err
+}
+
+// GetCurrentDietPlan returns the plan covering day, or nil when the user has
+// no plan on that day. This is the single place that decides which plan is
+// "current" for the rest of the API.
+func GetCurrentDietPlan(db *sql.DB, userID string, day time.Time) (*DietPlan, error) {
+	date := day.Format("2006-01-02")
+	row := db.QueryRow("SELECT PlanID, UserID, StartDate, EndDate, CalorieGoal FROM diet_plan WHERE UserID = ? AND StartDate <= ? AND EndDate >= ? ORDER BY StartDate DESC LIMIT 1", userID, date, date)
+	plan, err := scanDietPlan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return plan, err
+}
+
+// ListDietPlans returns all plans of a user, newest first.
+func ListDietPlans(db *sql.DB, userID string) ([]DietPlan, error) {
+	rows, err := db.Query("SELECT PlanID, UserID, StartDate, EndDate, CalorieGoal FROM diet_plan WHERE UserID = ? ORDER BY StartDate DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := []DietPlan{}
+	for rows.Next() {
+		plan, err := scanDietPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// lockUser starts a transaction holding the user's row, so that checks and
+// writes of the same user, such as overlapping plans or the AI quota, run
+// one request at a time.
+func lockUser(db *sql.DB, userID string) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = tx.QueryRow("SELECT UserID FROM users WHERE UserID = ? FOR UPDATE", userID).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// checkOverlap returns ErrPlanOverlap when another plan of the user shares
+// a day with plan.
+func checkOverlap(tx *sql.Tx, plan DietPlan) error {
+	var count int
+	query := "SELECT COUNT(*) FROM diet_plan WHERE UserID = ? AND PlanID <> ? AND StartDate <= ? AND EndDate >= ? FOR UPDATE"
+	err := tx.QueryRow(query, plan.UserID, plan.PlanID, plan.EndDate.Format("2006-01-02"), plan.StartDate.Format("2006-01-02")).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrPlanOverlap
+	}
+	return nil
+}
+
+// CreateDietPlan stores a new plan, assigning its PlanID.
+func CreateDietPlan(db *sql.DB, plan *DietPlan) error {
+	tx, err := lockUser(db, plan.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := createDietPlan(tx, plan); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func createDietPlan(tx *sql.Tx, plan *DietPlan) error {
+	if err := checkOverlap(tx, *plan); err != nil {
+		return err
+	}
+
+	planID, err := generateSequentialID(tx, "diet_plan", "PlanID", "DP")
+	if err != nil {
+		return err
+	}
+	plan.PlanID = planID
+
+	query := "INSERT INTO diet_plan (PlanID, UserID, StartDate, EndDate, CalorieGoal) VALUES (?, ?, ?, ?, ?)"
+	_, err = tx.Exec(query, plan.PlanID, plan.UserID, plan.StartDate.Format("2006-01-02"), plan.EndDate.Format("2006-01-02"), plan.CalorieGoal)
+	return err
+}
+
+// UpdateDietPlan changes the dates and goal of an existing plan.
+func UpdateDietPlan(db *sql.DB, plan DietPlan) error {
+	tx, err := lockUser(db, plan.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverlap(tx, plan); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query := "UPDATE diet_plan SET StartDate = ?, EndDate = ?, CalorieGoal = ? WHERE PlanID = ?"
+	if _, err := tx.Exec(query, plan.StartDate.Format("2006-01-02"), plan.EndDate.Format("2006-01-02"), plan.CalorieGoal, plan.PlanID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteDietPlan removes a plan with its targets and scheduled meals, all
+// or nothing.
+func DeleteDietPlan(db *sql.DB, planID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM diet_plan_target WHERE PlanID = ?",
+		"DELETE i FROM scheduled_meal_ingredient i JOIN scheduled_meal m ON i.ScheduleID = m.ScheduleID WHERE m.PlanID = ?",
+		"DELETE FROM scheduled_meal WHERE PlanID = ?",
+		"DELETE FROM diet_plan WHERE PlanID = ?",
+	} {
+		if _, err := tx.Exec(query, planID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
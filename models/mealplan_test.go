@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"nutrishe/models/dbtest"
+)
+
+func TestDeleteDietPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		failOn  string
+		want    []string
+		wantErr bool
+	}{
+		{"deleted", "", []string{"BEGIN", "DELETE FROM diet_plan_target", "DELETE i FROM scheduled_meal_ingredient", "DELETE FROM scheduled_meal", "DELETE FROM diet_plan WHERE", "COMMIT"}, false},
+		{"failure rolls back", "DELETE FROM scheduled_meal WHERE", []string{"BEGIN", "DELETE FROM diet_plan_target", "DELETE i FROM scheduled_meal_ingredient", "DELETE FROM scheduled_meal", "ROLLBACK"}, true},
+	}
+	for _, tt := range tests {
+		db, stub := dbtest.Open(func(query string, args []driver.Value) ([][]driver.Value, error) {
+			if tt.failOn != "" && strings.HasPrefix(query, tt.failOn) {
+				return nil, errors.New("lock wait timeout")
+			}
+			return nil, nil
+		})
+
+		if err := DeleteDietPlan(db, "DP001"); (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v", tt.name, err)
+		}
+		statements := stub.Statements()
+		if len(statements) != len(tt.want) {
+			t.Fatalf("%s: statements = %q, want %q", tt.name, statements, tt.want)
+		}
+		for i, prefix := range tt.want {
+			if !strings.HasPrefix(statements[i], prefix) {
+				t.Errorf("%s: statement %d = %q, want %q", tt.name, i, statements[i], prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParsedFood is one food read from a free-text meal description.
+type ParsedFood struct {
+	Text     string  `json:"text"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// tablespoonGrams is the weight assumed for a tablespoon of food.
+const tablespoonGrams = 15
+
+// Servings converts the quantity into servings of a catalogue food whose
+// serving weighs servingGrams. Weights are divided by the serving; counted
+// units such as slices, plates or pieces are one serving each.
+func (f ParsedFood) Servings(servingGrams int) float64 {
+	grams := 0.0
+	switch f.Unit {
+	case "g":
+		grams = f.Quantity
+	case "tablespoon":
+		grams = f.Quantity * tablespoonGrams
+	default:
+		return f.Quantity
+	}
+	if servingGrams <= 0 {
+		return 1
+	}
+	return formatFloat(grams/float64(servingGrams), 2)
+}
+
+// mealTextSeparators split a description into foods, in English and
+// Indonesian.
+var mealTextSeparators = regexp.MustCompile(`(?i)\s*(?:,|;|\+|&|\n|\band\b|\bwith\b|\bplus\b|\bdan\b|\bdengan\b|\bpakai\b|\bsama\b)\s*`)
+
+// decimalComma matches Indonesian decimals such as "1,5", which must not
+// be split as two foods.
+var decimalComma = regexp.MustCompile(`(\d),(\d)`)
+
+var quantityWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "half": 0.5,
+	"satu": 1, "se": 1, "dua": 2, "tiga": 3, "empat": 4, "lima": 5, "setengah": 0.5,
+}
+
+// mealUnits maps unit words, singular and plural, to a canonical unit.
+var mealUnits = map[string]string{
+	"slice": "slice", "slices": "slice", "potong": "slice", "iris": "slice",
+	"cup": "cup", "cups": "cup", "gelas": "cup", "glass": "cup", "glasses": "cup",
+	"plate": "plate", "plates": "plate", "piring": "plate",
+	"bowl": "bowl", "bowls": "bowl", "mangkuk": "bowl", "mangkok": "bowl",
+	"piece": "piece", "pieces": "piece", "pcs": "piece", "buah": "piece", "biji": "piece",
+	"serving": "serving", "servings": "serving", "portion": "serving", "portions": "serving", "porsi": "serving",
+	"tbsp": "tablespoon", "tablespoon": "tablespoon", "tablespoons": "tablespoon", "sendok": "tablespoon", "sdm": "tablespoon",
+	"g": "g", "gram": "g", "grams": "g", "gr": "g",
+}
+
+// parseQuantity reads a number, fraction or number word.
+func parseQuantity(word string) (float64, bool) {
+	word = strings.ToLower(word)
+	if n, ok := quantityWords[word]; ok {
+		return n, true
+	}
+	if num, den, ok := strings.Cut(word, "/"); ok {
+		a, errA := strconv.ParseFloat(num, 64)
+		b, errB := strconv.ParseFloat(den, 64)
+		if errA == nil && errB == nil && b != 0 {
+			return a / b, true
+		}
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.Replace(word, ",", ".", 1), 64)
+	return n, err == nil && n > 0
+}
+
+// ParseMealText splits a description such as "2 slices of toast with peanut
+// butter and a latte" into foods with quantities. Foods without a quantity
+// count as one serving.
+func ParseMealText(text string) []ParsedFood {
+	foods := []ParsedFood{}
+	text = decimalComma.ReplaceAllString(text, "$1.$2")
+	for _, part := range mealTextSeparators.Split(text, -1) {
+		part = strings.TrimSpace(part)
+		words := strings.Fields(part)
+		if len(words) == 0 {
+			continue
+		}
+
+		food := ParsedFood{Text: part, Quantity: 1, Unit: "serving"}
+		if n, ok := parseQuantity(words[0]); ok {
+			food.Quantity = n
+			words = words[1:]
+		} else if n, err := strconv.ParseFloat(strings.TrimRightFunc(words[0], isLetter), 64); err == nil && n > 0 {
+			// Amounts written together with their unit, like "150g"
+			unit := strings.TrimLeftFunc(words[0], isDigitOrDot)
+			if canonical, ok := mealUnits[strings.ToLower(unit)]; ok {
+				food.Quantity, food.Unit = n, canonical
+				words = words[1:]
+			}
+		}
+		if len(words) > 0 {
+			if unit, ok := mealUnits[strings.ToLower(words[0])]; ok {
+				food.Unit = unit
+				words = words[1:]
+			}
+		}
+		if len(words) > 0 && strings.EqualFold(words[0], "of") {
+			words = words[1:]
+		}
+
+		food.Name = strings.Join(words, " ")
+		if food.Name != "" {
+			foods = append(foods, food)
+		}
+	}
+	return foods
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isDigitOrDot(r rune) bool {
+	return r >= '0' && r <= '9' || r == '.'
+}
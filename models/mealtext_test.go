@@ -0,0 +1,70 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMealText(t *testing.T) {
+	tests := []struct {
+		text string
+		want []ParsedFood
+	}{
+		{
+			text: "2 slices of toast with peanut butter and a latte",
+			want: []ParsedFood{
+				{Text: "2 slices of toast", Name: "toast", Quantity: 2, Unit: "slice"},
+				{Text: "peanut butter", Name: "peanut butter", Quantity: 1, Unit: "serving"},
+				{Text: "a latte", Name: "latte", Quantity: 1, Unit: "serving"},
+			},
+		},
+		{
+			text: "nasi putih 1 piring dan dua potong tempe goreng",
+			want: []ParsedFood{
+				{Text: "nasi putih 1 piring", Name: "nasi putih 1 piring", Quantity: 1, Unit: "serving"},
+				{Text: "dua potong tempe goreng", Name: "tempe goreng", Quantity: 2, Unit: "slice"},
+			},
+		},
+		{
+			text: "150g chicken breast, 1/2 cup rice",
+			want: []ParsedFood{
+				{Text: "150g chicken breast", Name: "chicken breast", Quantity: 150, Unit: "g"},
+				{Text: "1/2 cup rice", Name: "rice", Quantity: 0.5, Unit: "cup"},
+			},
+		},
+		{
+			text: "1,5 porsi soto; setengah mangkok bubur",
+			want: []ParsedFood{
+				{Text: "1.5 porsi soto", Name: "soto", Quantity: 1.5, Unit: "serving"},
+				{Text: "setengah mangkok bubur", Name: "bubur", Quantity: 0.5, Unit: "bowl"},
+			},
+		},
+		{text: " , and ", want: []ParsedFood{}},
+		{text: "2 slices", want: []ParsedFood{}},
+	}
+	for _, tt := range tests {
+		if got := ParseMealText(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMealText(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParsedFoodServings(t *testing.T) {
+	tests := []struct {
+		food         ParsedFood
+		servingGrams int
+		want         float64
+	}{
+		{ParsedFood{Quantity: 2, Unit: "slice"}, 30, 2},
+		{ParsedFood{Quantity: 1.5, Unit: "serving"}, 100, 1.5},
+		{ParsedFood{Quantity: 150, Unit: "g"}, 100, 1.5},
+		{ParsedFood{Quantity: 50, Unit: "g"}, 150, 0.33},
+		{ParsedFood{Quantity: 2, Unit: "tablespoon"}, 20, 1.5},
+		{ParsedFood{Quantity: 150, Unit: "g"}, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.food.Servings(tt.servingGrams); got != tt.want {
+			t.Errorf("%+v.Servings(%d) = %v, want %v", tt.food, tt.servingGrams, got, tt.want)
+		}
+	}
+}
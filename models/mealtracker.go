@@ -2,15 +2,74 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"nutrishe/entity"
 )
 
+// ErrFoodNotFound is returned when a food is not in the catalogue.
+var ErrFoodNotFound = errors.New("food not found")
+
+// CreateNewMeal adds a food with its tags, names, nutrients and price in
+// one transaction.
 func CreateNewMeal(db *sql.DB, data entity.Food) error {
-	_, err := db.Exec("INSERT INTO food (FoodID, Name, Serving, Calories, Type) VALUE (?, ?, ?, ?, 'food')", data.FoodID, data.Name, data.Serving, data.Calories)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := createNewMeal(tx, data); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
+func createNewMeal(tx *sql.Tx, data entity.Food) error {
+	_, err := tx.Exec("INSERT INTO food (FoodID, Name, Serving, Calories, Type) VALUE (?, ?, ?, ?, 'food')", data.FoodID, data.Name, data.Serving, data.Calories)
 	if err != nil {
 		return err
 	}
 
+	if len(data.Tags) > 0 {
+		if err := setFoodTags(tx, data.FoodID, data.Tags); err != nil {
+			return err
+		}
+	}
+
+	for lang, name := range data.Names {
+		_, err := tx.Exec("REPLACE INTO food_translation (FoodID, Lang, Name) VALUES (?, ?, ?)", data.FoodID, lang, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	if n := data.Nutrients; n != nil {
+		_, err := tx.Exec("REPLACE INTO food_nutrient (FoodID, Sugar, SaturatedFat, Sodium, Iron) VALUES (?, ?, ?, ?, ?)", data.FoodID, n.Sugar, n.SaturatedFat, n.Sodium, n.Iron)
+		if err != nil {
+			return err
+		}
+	}
+
+	if data.PricePerServing != nil {
+		_, err := tx.Exec("REPLACE INTO food_price (FoodID, PricePerServing) VALUES (?, ?)", data.FoodID, *data.PricePerServing)
+		return err
+	}
+
 	return nil
 }
+
+// SetFoodPrice stores the price of one serving of a food, in rupiah, or
+// returns ErrFoodNotFound.
+func SetFoodPrice(db *sql.DB, foodID string, price int) error {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM food WHERE FoodID = ?", foodID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrFoodNotFound
+	}
+
+	_, err = db.Exec("REPLACE INTO food_price (FoodID, PricePerServing) VALUES (?, ?)", foodID, price)
+	return err
+}
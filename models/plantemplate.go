@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrTemplateNotFound is returned when a plan template does not exist.
+var ErrTemplateNotFound = errors.New("diet plan template not found")
+
+// DietPlanTemplate is a reusable plan curated by a nutritionist
+type DietPlanTemplate struct {
+	TemplateID          int       `json:"template_id"`
+	Name                string    `json:"name"`
+	Guidance            string    `json:"guidance"`
+	CalorieGoal         int       `json:"calorie_goal"`
+	ProteinPercent      int       `json:"protein_percent"`
+	CarbohydratePercent int       `json:"carbohydrate_percent"`
+	FatPercent          int       `json:"fat_percent"`
+	DurationDays        int       `json:"duration_days"`
+	AuthorID            string    `json:"author_id"`
+	CreatedAt           time.Time `json:"created_at"`
+	Foods               []string  `json:"foods"`
+}
+
+// DietPlanTarget holds the macro targets of a plan, in grams per day
+type DietPlanTarget struct {
+	PlanID           string  `json:"plan_id"`
+	TemplateID       *int    `json:"template_id"`
+	ProteinGoal      float64 `json:"protein_goal"`
+	CarbohydrateGoal float64 `json:"carbohydrate_goal"`
+	FatGoal          float64 `json:"fat_goal"`
+}
+
+// MacroTargets converts the template's macro split into grams for a daily
+// calorie goal, using 4 kcal/g for protein and carbohydrates and 9 kcal/g
+// for fat.
+func (t DietPlanTemplate) MacroTargets(calorieGoal int) DietPlanTarget {
+	calories := float64(calorieGoal)
+	templateID := t.TemplateID
+	return DietPlanTarget{
+		TemplateID:       &templateID,
+		ProteinGoal:      formatFloat(calories*float64(t.ProteinPercent)/100/4, 1),
+		CarbohydrateGoal: formatFloat(calories*float64(t.CarbohydratePercent)/100/4, 1),
+		FatGoal:          formatFloat(calories*float64(t.FatPercent)/100/9, 1),
+	}
+}
+
+func IsNutritionist(db *sql.DB, userID string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM nutritionist WHERE UserID = ?", userID).Scan(&exists)
+	return exists, err
+}
+
+func CreateDietPlanTemplate(db *sql.DB, t *DietPlanTemplate) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	t.CreatedAt = time.Now()
+	query := `INSERT INTO diet_plan_template (Name, Guidance, CalorieGoal, ProteinPercent, CarbohydratePercent, FatPercent, DurationDays, AuthorID, CreatedAt)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, t.Name, t.Guidance, t.CalorieGoal, t.ProteinPercent, t.CarbohydratePercent, t.FatPercent, t.DurationDays, t.AuthorID, t.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	t.TemplateID = int(id)
+
+	for _, foodID := range t.Foods {
+		_, err = tx.Exec("INSERT INTO diet_plan_template_food (TemplateID, FoodID) VALUES (?, ?)", t.TemplateID, foodID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*DietPlanTemplate, error) {
+	var t DietPlanTemplate
+	var createdAt string
+	err := row.Scan(&t.TemplateID, &t.Name, &t.Guidance, &t.CalorieGoal, &t.ProteinPercent, &t.CarbohydratePercent, &t.FatPercent, &t.DurationDays, &t.AuthorID, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedAt, err = parseDBDate(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Foods = []string{}
+	return &t, nil
+}
+
+const templateColumns = "TemplateID, Name, Guidance, CalorieGoal, ProteinPercent, CarbohydratePercent, FatPercent, DurationDays, AuthorID, CreatedAt"
+
+// GetDietPlanTemplate returns a template with its recommended foods.
+func GetDietPlanTemplate(db *sql.DB, templateID int) (*DietPlanTemplate, error) {
+	row := db.QueryRow("SELECT "+templateColumns+" FROM diet_plan_template WHERE TemplateID = ?", templateID)
+	t, err := scanTemplate(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT FoodID FROM diet_plan_template_food WHERE TemplateID = ? ORDER BY FoodID", templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var foodID string
+		if err := rows.Scan(&foodID); err != nil {
+			return nil, err
+		}
+		t.Foods = append(t.Foods, foodID)
+	}
+	return t, rows.Err()
+}
+
+// ListDietPlanTemplates returns all templates with their recommended foods.
+func ListDietPlanTemplates(db *sql.DB) ([]DietPlanTemplate, error) {
+	rows, err := db.Query("SELECT " + templateColumns + " FROM diet_plan_template ORDER BY Name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []DietPlanTemplate{}
+	index := make(map[int]int)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[t.TemplateID] = len(templates)
+		templates = append(templates, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	foodRows, err := db.Query("SELECT TemplateID, FoodID FROM diet_plan_template_food ORDER BY FoodID")
+	if err != nil {
+		return nil, err
+	}
+	defer foodRows.Close()
+
+	for foodRows.Next() {
+		var templateID int
+		var foodID string
+		if err := foodRows.Scan(&templateID, &foodID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[templateID]; ok {
+			templates[i].Foods = append(templates[i].Foods, foodID)
+		}
+	}
+	return templates, foodRows.Err()
+}
+
+func SaveDietPlanTarget(db *sql.DB, target DietPlanTarget) error {
+	query := "REPLACE INTO diet_plan_target (PlanID, TemplateID, ProteinGoal, CarbohydrateGoal, FatGoal) VALUES (?, ?, ?, ?, ?)"
+	_, err := db.Exec(query, target.PlanID, target.TemplateID, target.ProteinGoal, target.CarbohydrateGoal, target.FatGoal)
+	return err
+}
+
+// GetDietPlanTarget returns the macro targets of a plan, or nil when the
+// plan only has a calorie goal.
+func GetDietPlanTarget(db *sql.DB, planID string) (*DietPlanTarget, error) {
+	var target DietPlanTarget
+	var templateID sql.NullInt64
+	row := db.QueryRow("SELECT PlanID, TemplateID, ProteinGoal, CarbohydrateGoal, FatGoal FROM diet_plan_target WHERE PlanID = ?", planID)
+	err := row.Scan(&target.PlanID, &templateID, &target.ProteinGoal, &target.CarbohydrateGoal, &target.FatGoal)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if templateID.Valid {
+		id := int(templateID.Int64)
+		target.TemplateID = &id
+	}
+	return &target, nil
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Menstrual cycle phases.
+const (
+	PhaseMenstrual  = "menstrual"
+	PhaseFollicular = "follicular"
+	PhaseOvulation  = "ovulation"
+	PhaseLuteal     = "luteal"
+)
+
+// defaultCycleLength is used when a cycle has no recorded duration.
+const defaultCycleLength = 28
+
+// recentDays is how far back recent intake is summarised.
+const recentDays = 7
+
+// NutritionProfile gathers everything stored about a user that matters for
+// personalised nutrition advice.
+type NutritionProfile struct {
+	UserID        string          `json:"user_id"`
+	Age           int             `json:"age"`
+	Height        float32         `json:"height"`
+	Weight        float32         `json:"weight"`
+	CalorieTarget int             `json:"calorie_target"`
+	CalorieSource string          `json:"calorie_source"`
+	Targets       *DietPlanTarget `json:"targets"`
+	Allergies     []string        `json:"allergies"`
+	Diets         []string        `json:"diets"`
+	Dislikes      []string        `json:"dislikes"`
+	CyclePhase    string          `json:"cycle_phase"`
+	CycleDay      int             `json:"cycle_day"`
+	RecentIntake  RecentIntake    `json:"recent_intake"`
+}
+
+// RecentIntake summarises the last recentDays days of logging.
+type RecentIntake struct {
+	Days            int      `json:"days"`
+	DaysLogged      int      `json:"days_logged"`
+	AverageCalories float64  `json:"average_calories"`
+	AverageProtein  float64  `json:"average_protein"`
+	AverageFiber    float64  `json:"average_fiber"`
+	FrequentFoods   []string `json:"frequent_foods"`
+}
+
+// ageOn returns the age in whole years on day. Birthdays are compared by
+// month and day, since day of the year shifts by one after Feb 29.
+func ageOn(birthdate, day time.Time) int {
+	age := day.Year() - birthdate.Year()
+	if day.Month() < birthdate.Month() || (day.Month() == birthdate.Month() && day.Day() < birthdate.Day()) {
+		age--
+	}
+	return age
+}
+
+// CyclePhaseOn returns the phase and day of a cycle that started on start.
+// Ovulation is assumed 14 days before the next period. An empty phase means
+// the cycle is overdue and the phase is unknown.
+func CyclePhaseOn(start time.Time, length int, day time.Time) (string, int) {
+	if length <= 0 {
+		length = defaultCycleLength
+	}
+	cycleDay := daysBetween(start, day)
+	ovulation := length - 14
+
+	switch {
+	case cycleDay < 1 || cycleDay > length:
+		return "", cycleDay
+	case cycleDay <= 5:
+		return PhaseMenstrual, cycleDay
+	case cycleDay < ovulation-1:
+		return PhaseFollicular, cycleDay
+	case cycleDay <= ovulation+1:
+		return PhaseOvulation, cycleDay
+	default:
+		return PhaseLuteal, cycleDay
+	}
+}
+
+// getCurrentCycle returns the start and duration of the user's latest cycle
+// that started on or before day.
+func getCurrentCycle(db *sql.DB, userID string, day time.Time) (time.Time, int, bool, error) {
+	var start string
+	var duration sql.NullInt64
+	query := "SELECT StartDate, CycleDuration FROM cycle WHERE UserID = ? AND StartDate <= ? ORDER BY StartDate DESC LIMIT 1"
+	err := db.QueryRow(query, userID, day.Format("2006-01-02")).Scan(&start, &duration)
+	if err == sql.ErrNoRows {
+		return time.Time{}, 0, false, nil
+	}
+	if err != nil {
+		return time.Time{}, 0, false, err
+	}
+
+	startDate, err := parseDBDate(start)
+	if err != nil {
+		return time.Time{}, 0, false, err
+	}
+	return startDate, int(duration.Int64), true, nil
+}
+
+// getFrequentFoods returns the names of the foods logged most often.
+func getFrequentFoods(db *sql.DB, userID string, from, to time.Time, limit int) ([]string, error) {
+	query := `SELECT f.Name FROM daily_meal dm
+	          JOIN meal_detail md ON md.TrackID = dm.TrackID
+	          JOIN food f ON f.FoodID = md.FoodID
+	          WHERE dm.UserID = ? AND dm.MealDate BETWEEN ? AND ?
+	          GROUP BY f.FoodID, f.Name
+	          ORDER BY COUNT(*) DESC, f.Name
+	          LIMIT ?`
+	rows, err := db.Query(query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetNutritionProfile builds the profile of a user as of day.
+func GetNutritionProfile(db *sql.DB, userID string, day time.Time) (*NutritionProfile, error) {
+	day = truncateDay(day)
+	profile := &NutritionProfile{UserID: userID}
+
+	user, err := GetUserByID(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		profile.Age = ageOn(user.Birthdate, day)
+		profile.Height = user.Height
+		profile.Weight = user.Weight
+	}
+
+	// The active diet plan wins over the last calculated requirement
+	plan, err := GetCurrentDietPlan(db, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	if plan != nil {
+		profile.CalorieTarget = plan.CalorieGoal
+		profile.CalorieSource = "diet_plan"
+		profile.Targets, err = GetDietPlanTarget(db, plan.PlanID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		calories, err := GetLatestCalorieRequirement(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		if calories > 0 {
+			profile.CalorieTarget = int(calories)
+			profile.CalorieSource = "calculated"
+		}
+	}
+
+	prefs, err := GetUserPreferences(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile.Allergies = prefs.Allergies
+	profile.Diets = prefs.Diets
+	profile.Dislikes = prefs.Dislikes
+
+	start, length, ok, err := getCurrentCycle(db, userID, day)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		profile.CyclePhase, profile.CycleDay = CyclePhaseOn(start, length, day)
+	}
+
+	from := day.AddDate(0, 0, -(recentDays - 1))
+	days, err := GetDailyIntake(db, userID, from, day)
+	if err != nil {
+		return nil, err
+	}
+	recent := RecentIntake{Days: recentDays, DaysLogged: len(days)}
+	if len(days) > 0 {
+		var calories int
+		var protein, fiber float64
+		for _, d := range days {
+			calories += d.TotalCalories
+			protein += d.Protein
+			fiber += d.Fiber
+		}
+		n := float64(len(days))
+		recent.AverageCalories = formatFloat(float64(calories)/n, 1)
+		recent.AverageProtein = formatFloat(protein/n, 1)
+		recent.AverageFiber = formatFloat(fiber/n, 1)
+	}
+	recent.FrequentFoods, err = getFrequentFoods(db, userID, from, day, 5)
+	if err != nil {
+		return nil, err
+	}
+	profile.RecentIntake = recent
+
+	return profile, nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import "testing"
+
+func TestAgeOn(t *testing.T) {
+	tests := []struct {
+		birthdate, day string
+		want           int
+	}{
+		{"2000-03-01", "2023-03-01", 23},
+		{"2000-03-01", "2023-02-28", 22},
+		{"2000-03-01", "2024-02-29", 23},
+		{"2000-03-01", "2024-03-01", 24},
+		{"2000-02-29", "2023-02-28", 22},
+		{"2000-02-29", "2023-03-01", 23},
+		{"2000-02-29", "2024-02-29", 24},
+		{"1990-12-31", "2024-01-01", 33},
+	}
+	for _, tt := range tests {
+		if got := ageOn(date(tt.birthdate), date(tt.day)); got != tt.want {
+			t.Errorf("ageOn(%s, %s) = %d, want %d", tt.birthdate, tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestCyclePhaseOn(t *testing.T) {
+	tests := []struct {
+		length    int
+		day       string
+		wantPhase string
+		wantDay   int
+	}{
+		{28, "2024-03-01", PhaseMenstrual, 1},
+		{28, "2024-03-05", PhaseMenstrual, 5},
+		{28, "2024-03-06", PhaseFollicular, 6},
+		{28, "2024-03-12", PhaseFollicular, 12},
+		{28, "2024-03-13", PhaseOvulation, 13},
+		{28, "2024-03-15", PhaseOvulation, 15},
+		{28, "2024-03-16", PhaseLuteal, 16},
+		{28, "2024-03-28", PhaseLuteal, 28},
+		{28, "2024-03-29", "", 29},
+		{28, "2024-02-29", "", 0},
+		{0, "2024-03-16", PhaseLuteal, 16},
+		{35, "2024-03-19", PhaseFollicular, 19},
+		{35, "2024-03-20", PhaseOvulation, 20},
+		{35, "2024-03-23", PhaseLuteal, 23},
+	}
+	for _, tt := range tests {
+		phase, day := CyclePhaseOn(date("2024-03-01"), tt.length, date(tt.day))
+		if phase != tt.wantPhase || day != tt.wantDay {
+			t.Errorf("CyclePhaseOn(length %d, %s) = %q day %d, want %q day %d", tt.length, tt.day, phase, day, tt.wantPhase, tt.wantDay)
+		}
+	}
+}
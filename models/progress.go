@@ -0,0 +1,125 @@
+package models
+
+import "time"
+
+// kcalPerKg is the approximate energy content of one kilogram of body fat.
+const kcalPerKg = 7700.0
+
+// PlanDay compares the goal of a plan day with the logged intake.
+type PlanDay struct {
+	Date     string `json:"date"`
+	Goal     int    `json:"goal"`
+	Actual   int    `json:"actual"`
+	Logged   bool   `json:"logged"`
+	OnTarget bool   `json:"on_target"`
+}
+
+// PlanProjection estimates the outcome of a plan at its end date if the
+// user keeps eating like the logged days so far.
+type PlanProjection struct {
+	AverageDailyCalories   float64  `json:"average_daily_calories"`
+	ProjectedTotalCalories int      `json:"projected_total_calories"`
+	GoalTotalCalories      int      `json:"goal_total_calories"`
+	ProjectedDifference    int      `json:"projected_difference"`
+	ProjectedWeightChange  *float64 `json:"projected_weight_change_kg"`
+}
+
+// PlanProgress is the progress of a diet plan up to today.
+type PlanProgress struct {
+	Plan             DietPlan       `json:"plan"`
+	Status           string         `json:"status"`
+	TotalDays        int            `json:"total_days"`
+	DaysElapsed      int            `json:"days_elapsed"`
+	DaysRemaining    int            `json:"days_remaining"`
+	DaysLogged       int            `json:"days_logged"`
+	DaysOnTarget     int            `json:"days_on_target"`
+	AdherencePercent float64        `json:"adherence_percent"`
+	CurrentStreak    int            `json:"current_streak"`
+	BestStreak       int            `json:"best_streak"`
+	Days             []PlanDay      `json:"days"`
+	Projection       PlanProjection `json:"projection"`
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(truncateDay(to).Sub(truncateDay(from)).Hours()/24) + 1
+}
+
+// ComputePlanProgress compares the plan's goal with the logged days. tdee
+// is the user's daily energy expenditure, or 0 when unknown, in which case
+// no weight change is projected.
+func ComputePlanProgress(plan DietPlan, days []DailyIntake, today time.Time, tdee float64) PlanProgress {
+	start, end := truncateDay(plan.StartDate), truncateDay(plan.EndDate)
+	today = truncateDay(today)
+
+	progress := PlanProgress{
+		Plan:      plan,
+		Status:    plan.Status(today),
+		TotalDays: daysBetween(start, end),
+		Days:      []PlanDay{},
+	}
+
+	last := today
+	if last.After(end) {
+		last = end
+	}
+	if !last.Before(start) {
+		progress.DaysElapsed = daysBetween(start, last)
+	}
+	progress.DaysRemaining = progress.TotalDays - progress.DaysElapsed
+
+	intake := make(map[string]int)
+	for _, day := range days {
+		intake[truncateDay(day.Date).Format("2006-01-02")] = day.TotalCalories
+	}
+
+	totalLogged := 0
+	streak := 0
+	for i := 0; i < progress.DaysElapsed; i++ {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		actual, logged := intake[date]
+		day := PlanDay{
+			Date:     date,
+			Goal:     plan.CalorieGoal,
+			Actual:   actual,
+			Logged:   logged,
+			OnTarget: logged && IsOnTarget(actual, plan.CalorieGoal),
+		}
+		progress.Days = append(progress.Days, day)
+
+		if logged {
+			progress.DaysLogged++
+			totalLogged += actual
+		}
+		if day.OnTarget {
+			progress.DaysOnTarget++
+			streak++
+			if streak > progress.BestStreak {
+				progress.BestStreak = streak
+			}
+		} else if !(day.Date == today.Format("2006-01-02") && !logged) {
+			// Today only breaks the streak once something is logged
+			streak = 0
+		}
+	}
+	progress.CurrentStreak = streak
+
+	if progress.DaysElapsed > 0 {
+		progress.AdherencePercent = formatFloat(float64(progress.DaysOnTarget)*100/float64(progress.DaysElapsed), 2)
+	}
+
+	projection := PlanProjection{GoalTotalCalories: plan.CalorieGoal * progress.TotalDays}
+	if progress.DaysLogged > 0 {
+		average := float64(totalLogged) / float64(progress.DaysLogged)
+		projection.AverageDailyCalories = formatFloat(average, 2)
+		projection.ProjectedTotalCalories = totalLogged + int(average*float64(progress.TotalDays-progress.DaysLogged))
+		projection.ProjectedDifference = projection.ProjectedTotalCalories - projection.GoalTotalCalories
+
+		if tdee > 0 {
+			change := formatFloat((average-tdee)*float64(progress.TotalDays)/kcalPerKg, 2)
+			projection.ProjectedWeightChange = &change
+		}
+	}
+	progress.Projection = projection
+
+	return progress
+}
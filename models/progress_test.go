@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestComputePlanProgress(t *testing.T) {
+	plan := DietPlan{PlanID: "DP001", UserID: "US001", StartDate: date("2024-03-01"), EndDate: date("2024-03-10"), CalorieGoal: 2000}
+	days := []DailyIntake{
+		{Date: date("2024-03-01"), TotalCalories: 2000},
+		{Date: date("2024-03-02"), TotalCalories: 2100},
+		{Date: date("2024-03-03"), TotalCalories: 1500},
+		{Date: date("2024-03-04"), TotalCalories: 1950},
+	}
+
+	tests := []struct {
+		name           string
+		today          string
+		tdee           float64
+		status         string
+		elapsed        int
+		onTarget       int
+		adherence      float64
+		current, best  int
+		projected      int
+		weightChange   float64
+		noWeightChange bool
+	}{
+		{
+			name:  "today not logged yet keeps the streak",
+			today: "2024-03-05", tdee: 2200,
+			status: PlanActive, elapsed: 5, onTarget: 3, adherence: 60, current: 1, best: 2,
+			projected: 18875, weightChange: -0.41,
+		},
+		{
+			name:   "ended plan without expenditure",
+			today:  "2024-03-20",
+			status: PlanPast, elapsed: 10, onTarget: 3, adherence: 30, current: 0, best: 2,
+			projected: 18875, noWeightChange: true,
+		},
+		{
+			name:  "upcoming plan",
+			today: "2024-02-25", tdee: 2200,
+			status: PlanUpcoming, elapsed: 0, projected: 0, noWeightChange: true,
+		},
+	}
+	for _, tt := range tests {
+		var logged []DailyIntake
+		if tt.elapsed > 0 {
+			logged = days
+		}
+		p := ComputePlanProgress(plan, logged, date(tt.today), tt.tdee)
+		if p.Status != tt.status || p.TotalDays != 10 || p.DaysElapsed != tt.elapsed || p.DaysRemaining != 10-tt.elapsed || len(p.Days) != tt.elapsed {
+			t.Errorf("%s: status %s, total %d, elapsed %d, remaining %d, days %d", tt.name, p.Status, p.TotalDays, p.DaysElapsed, p.DaysRemaining, len(p.Days))
+		}
+		if p.DaysOnTarget != tt.onTarget || p.AdherencePercent != tt.adherence {
+			t.Errorf("%s: on target %d (%.2f%%), want %d (%.2f%%)", tt.name, p.DaysOnTarget, p.AdherencePercent, tt.onTarget, tt.adherence)
+		}
+		if p.CurrentStreak != tt.current || p.BestStreak != tt.best {
+			t.Errorf("%s: streak %d best %d, want %d best %d", tt.name, p.CurrentStreak, p.BestStreak, tt.current, tt.best)
+		}
+		if p.Projection.ProjectedTotalCalories != tt.projected || p.Projection.GoalTotalCalories != 20000 {
+			t.Errorf("%s: projection %+v", tt.name, p.Projection)
+		}
+		switch change := p.Projection.ProjectedWeightChange; {
+		case tt.noWeightChange && change != nil:
+			t.Errorf("%s: weight change %v, want none", tt.name, *change)
+		case !tt.noWeightChange && (change == nil || *change != tt.weightChange):
+			t.Errorf("%s: weight change %v, want %v", tt.name, change, tt.weightChange)
+		}
+	}
+}
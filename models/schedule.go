@@ -0,0 +1,246 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"nutrishe/entity"
+)
+
+// ErrScheduledMealNotFound is returned when a scheduled meal does not exist.
+var ErrScheduledMealNotFound = errors.New("scheduled meal not found")
+
+// ErrScheduledMealEaten is returned when a scheduled meal is already eaten.
+var ErrScheduledMealEaten = errors.New("scheduled meal already eaten")
+
+// ScheduledMeal is one item of an accepted meal plan, planned for a date.
+type ScheduledMeal struct {
+	ScheduleID    int        `json:"schedule_id"`
+	UserID        string     `json:"user_id"`
+	PlanID        string     `json:"plan_id"`
+	MealDate      string     `json:"meal_date"`
+	MealName      string     `json:"meal_name"`
+	ItemName      string     `json:"item_name"`
+	Portion       string     `json:"portion"`
+	FoodID        *string    `json:"food_id"`
+	Calories      int        `json:"calories"`
+	Protein       float64    `json:"protein"`
+	Carbohydrates float64    `json:"carbohydrates"`
+	Fat           float64    `json:"fat"`
+	Eaten         bool       `json:"eaten"`
+	EatenAt       *time.Time `json:"eaten_at"`
+}
+
+// SaveMealPlanSchedule stores every item of plan, day 1 on start, for the
+// diet plan planID. Meals not yet eaten on those dates are replaced, so
+// accepting a new plan for the same days does not duplicate meals.
+func SaveMealPlanSchedule(db *sql.DB, userID, planID string, start time.Time, plan *entity.MealPlan) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for i, day := range plan.Days {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		_, err = tx.Exec(`DELETE i FROM scheduled_meal_ingredient i JOIN scheduled_meal m ON i.ScheduleID = m.ScheduleID
+		                  WHERE m.UserID = ? AND m.MealDate = ? AND m.Eaten = 0`, userID, date)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM scheduled_meal WHERE UserID = ? AND MealDate = ? AND Eaten = 0", userID, date)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				var foodID *string
+				if item.FoodID != "" {
+					id := item.FoodID
+					foodID = &id
+				}
+
+				query := `INSERT INTO scheduled_meal (UserID, PlanID, MealDate, MealName, ItemName, Portion, FoodID, Calories, Protein, Carbohydrates, Fat)
+				          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+				result, err := tx.Exec(query, userID, planID, date, meal.Name, item.Name, item.Portion, foodID, item.Calories, item.Protein, item.Carbohydrates, item.Fat)
+				if err != nil {
+					tx.Rollback()
+					return err
+				}
+				scheduleID, err := result.LastInsertId()
+				if err != nil {
+					tx.Rollback()
+					return err
+				}
+
+				for _, ing := range itemIngredients(item) {
+					category := ing.Category
+					if !IsAisle(category) {
+						category = CategorizeIngredient(ing.Name)
+					}
+					_, err = tx.Exec("INSERT INTO scheduled_meal_ingredient (ScheduleID, Name, Quantity, Unit, Category) VALUES (?, ?, ?, ?, ?)",
+						scheduleID, ing.Name, ing.Quantity, ing.Unit, category)
+					if err != nil {
+						tx.Rollback()
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+const scheduledMealColumns = "ScheduleID, UserID, PlanID, MealDate, MealName, ItemName, Portion, FoodID, Calories, Protein, Carbohydrates, Fat, Eaten, EatenAt"
+
+func scanScheduledMeal(row interface{ Scan(...interface{}) error }) (*ScheduledMeal, error) {
+	var meal ScheduledMeal
+	var mealDate string
+	var foodID, eatenAt sql.NullString
+	err := row.Scan(&meal.ScheduleID, &meal.UserID, &meal.PlanID, &mealDate, &meal.MealName, &meal.ItemName, &meal.Portion,
+		&foodID, &meal.Calories, &meal.Protein, &meal.Carbohydrates, &meal.Fat, &meal.Eaten, &eatenAt)
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseDBDate(mealDate)
+	if err != nil {
+		return nil, err
+	}
+	meal.MealDate = date.Format("2006-01-02")
+
+	if foodID.Valid {
+		meal.FoodID = &foodID.String
+	}
+	if eatenAt.Valid {
+		t, err := parseDBDate(eatenAt.String)
+		if err != nil {
+			return nil, err
+		}
+		meal.EatenAt = &t
+	}
+	return &meal, nil
+}
+
+// GetScheduledMeals returns the planned meals of a user between two dates.
+func GetScheduledMeals(db *sql.DB, userID string, from, to time.Time) ([]ScheduledMeal, error) {
+	query := "SELECT " + scheduledMealColumns + " FROM scheduled_meal WHERE UserID = ? AND MealDate BETWEEN ? AND ? ORDER BY MealDate, ScheduleID"
+	rows, err := db.Query(query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meals := []ScheduledMeal{}
+	for rows.Next() {
+		meal, err := scanScheduledMeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		meals = append(meals, *meal)
+	}
+	return meals, rows.Err()
+}
+
+// GetScheduledIngredients returns the ingredients of the meals a user still
+// has to eat between two dates.
+func GetScheduledIngredients(db *sql.DB, userID string, from, to time.Time) ([]entity.Ingredient, error) {
+	query := `SELECT i.Name, i.Quantity, i.Unit, i.Category FROM scheduled_meal_ingredient i
+	          JOIN scheduled_meal m ON i.ScheduleID = m.ScheduleID
+	          WHERE m.UserID = ? AND m.MealDate BETWEEN ? AND ? AND m.Eaten = 0
+	          ORDER BY m.MealDate, m.ScheduleID`
+	rows, err := db.Query(query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ingredients := []entity.Ingredient{}
+	for rows.Next() {
+		var ing entity.Ingredient
+		if err := rows.Scan(&ing.Name, &ing.Quantity, &ing.Unit, &ing.Category); err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, ing)
+	}
+	return ingredients, rows.Err()
+}
+
+// GetScheduledMeal returns a planned meal by ID, or ErrScheduledMealNotFound.
+func GetScheduledMeal(db *sql.DB, scheduleID int) (*ScheduledMeal, error) {
+	row := db.QueryRow("SELECT "+scheduledMealColumns+" FROM scheduled_meal WHERE ScheduleID = ?", scheduleID)
+	meal, err := scanScheduledMeal(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduledMealNotFound
+	}
+	return meal, err
+}
+
+// MarkScheduledMealEaten logs the meal's food through the normal logging
+// path, as many servings as its portion is, and marks the meal as eaten in
+// the same transaction.
+func MarkScheduledMealEaten(db *sql.DB, meal *ScheduledMeal) error {
+	date, err := parseDBDate(meal.MealDate)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Claim the meal first so that concurrent requests log it only once
+	now := time.Now()
+	result, err := tx.Exec("UPDATE scheduled_meal SET Eaten = 1, EatenAt = ? WHERE ScheduleID = ? AND Eaten = 0", now.Format("2006-01-02 15:04:05"), meal.ScheduleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if claimed == 0 {
+		tx.Rollback()
+		return ErrScheduledMealEaten
+	}
+
+	servings, err := scheduledServings(tx, *meal.FoodID, meal.Portion)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = logMealFoods(tx, meal.UserID, date, []LoggedFood{{FoodID: *meal.FoodID, Servings: servings}})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	meal.Eaten = true
+	meal.EatenAt = &now
+	return nil
+}
+
+// scheduledServings is how many servings of a food a planned portion is,
+// one when the portion cannot be read.
+func scheduledServings(tx *sql.Tx, foodID, portion string) (float64, error) {
+	var serving int
+	err := tx.QueryRow("SELECT Serving FROM food WHERE FoodID = ?", foodID).Scan(&serving)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if servings := PortionServings(portion, serving); servings > 0 {
+		return servings, nil
+	}
+	return 1, nil
+}
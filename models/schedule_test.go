@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"nutrishe/models/dbtest"
+)
+
+func TestMarkScheduledMealEaten(t *testing.T) {
+	tests := []struct {
+		portion string
+		want    float64
+	}{
+		{"1 plate", 1},
+		{"2 pieces", 2},
+		{"300 g", 3},
+		{"a little", 1},
+		{"0 g", 1},
+	}
+	for _, tt := range tests {
+		var logged float64
+		db, _ := dbtest.Open(func(query string, args []driver.Value) ([][]driver.Value, error) {
+			switch {
+			case strings.HasPrefix(query, "SELECT Serving FROM food"):
+				return [][]driver.Value{{int64(100)}}, nil
+			case strings.HasPrefix(query, "SELECT TrackID FROM daily_meal"):
+				return [][]driver.Value{{"TR001"}}, nil
+			case strings.HasPrefix(query, "SELECT COUNT(*) > 0 FROM food"):
+				return [][]driver.Value{{true}}, nil
+			case strings.HasPrefix(query, "SELECT COUNT(*) > 0 FROM meal_detail"):
+				return [][]driver.Value{{false}}, nil
+			case strings.HasPrefix(query, "SELECT COALESCE(ROUND"):
+				return [][]driver.Value{{int64(0)}}, nil
+			case strings.HasPrefix(query, "REPLACE INTO meal_detail_quantity"):
+				logged = args[2].(float64)
+			}
+			return nil, nil
+		})
+
+		foodID := "F001"
+		meal := &ScheduledMeal{ScheduleID: 1, UserID: "US001", MealDate: "2024-03-01", Portion: tt.portion, FoodID: &foodID}
+		if err := MarkScheduledMealEaten(db, meal); err != nil {
+			t.Fatalf("%q: %v", tt.portion, err)
+		}
+		if logged != tt.want {
+			t.Errorf("%q: logged %v servings, want %v", tt.portion, logged, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// schema lists the tables that are owned by the API itself. The original
+// tables (users, food, daily_meal, ...) are managed outside of this service,
+// so only additional tables are created here, and only when missing.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS food_tag (
+		FoodID char(5) NOT NULL,
+		Tag varchar(20) NOT NULL,
+		PRIMARY KEY (FoodID, Tag)
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_preference (
+		UserID char(5) NOT NULL,
+		Kind varchar(10) NOT NULL,
+		Tag varchar(20) NOT NULL,
+		PRIMARY KEY (UserID, Kind, Tag)
+	)`,
+	`CREATE TABLE IF NOT EXISTS food_translation (
+		FoodID char(5) NOT NULL,
+		Lang char(2) NOT NULL,
+		Name varchar(255) NOT NULL,
+		PRIMARY KEY (FoodID, Lang)
+	)`,
+	`CREATE TABLE IF NOT EXISTS food_nutrient (
+		FoodID char(5) NOT NULL PRIMARY KEY,
+		Sugar float NULL,
+		SaturatedFat float NULL,
+		Sodium int NULL,
+		Iron float NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS nutritionist (
+		UserID char(5) NOT NULL PRIMARY KEY
+	)`,
+	`CREATE TABLE IF NOT EXISTS diet_plan_template (
+		TemplateID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		Name varchar(255) NOT NULL,
+		Guidance text NOT NULL,
+		CalorieGoal int NOT NULL,
+		ProteinPercent int NOT NULL,
+		CarbohydratePercent int NOT NULL,
+		FatPercent int NOT NULL,
+		DurationDays int NOT NULL,
+		AuthorID char(5) NOT NULL,
+		CreatedAt datetime NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS diet_plan_template_food (
+		TemplateID int NOT NULL,
+		FoodID char(5) NOT NULL,
+		PRIMARY KEY (TemplateID, FoodID)
+	)`,
+	`CREATE TABLE IF NOT EXISTS diet_plan_target (
+		PlanID char(5) NOT NULL PRIMARY KEY,
+		TemplateID int NULL,
+		ProteinGoal float NOT NULL,
+		CarbohydrateGoal float NOT NULL,
+		FatGoal float NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_disliked_food (
+		UserID char(5) NOT NULL,
+		Name varchar(255) NOT NULL,
+		PRIMARY KEY (UserID, Name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS scheduled_meal (
+		ScheduleID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		UserID char(5) NOT NULL,
+		PlanID char(5) NOT NULL,
+		MealDate date NOT NULL,
+		MealName varchar(55) NOT NULL,
+		ItemName varchar(255) NOT NULL,
+		Portion varchar(100) NOT NULL,
+		FoodID char(5) NULL,
+		Calories int NOT NULL,
+		Protein float NOT NULL,
+		Carbohydrates float NOT NULL,
+		Fat float NOT NULL,
+		Eaten tinyint(1) NOT NULL DEFAULT 0,
+		EatenAt datetime NULL,
+		INDEX (UserID, MealDate)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ai_usage (
+		UsageID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		UserID char(5) NOT NULL,
+		Endpoint varchar(55) NOT NULL,
+		PromptTokens int NOT NULL,
+		OutputTokens int NOT NULL,
+		Cached tinyint(1) NOT NULL DEFAULT 0,
+		CreatedAt datetime NOT NULL,
+		INDEX (UserID, CreatedAt)
+	)`,
+	`CREATE TABLE IF NOT EXISTS chat_thread (
+		ThreadID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		UserID char(5) NOT NULL,
+		Title varchar(100) NOT NULL,
+		CreatedAt datetime NOT NULL,
+		UpdatedAt datetime NOT NULL,
+		INDEX (UserID, UpdatedAt)
+	)`,
+	`CREATE TABLE IF NOT EXISTS chat_message (
+		MessageID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		ThreadID int NOT NULL,
+		Role varchar(10) NOT NULL,
+		Content text NOT NULL,
+		CreatedAt datetime NOT NULL,
+		INDEX (ThreadID, MessageID)
+	)`,
+	`CREATE TABLE IF NOT EXISTS scheduled_meal_ingredient (
+		ScheduleID int NOT NULL,
+		Name varchar(100) NOT NULL,
+		Quantity float NOT NULL,
+		Unit varchar(20) NOT NULL,
+		Category varchar(20) NOT NULL,
+		INDEX (ScheduleID)
+	)`,
+	`CREATE TABLE IF NOT EXISTS pantry_item (
+		UserID char(5) NOT NULL,
+		Name varchar(100) NOT NULL,
+		Quantity float NOT NULL DEFAULT 0,
+		Unit varchar(20) NOT NULL DEFAULT '',
+		PRIMARY KEY (UserID, Name)
+	)`,
+	`CREATE TABLE IF NOT EXISTS food_price (
+		FoodID char(5) NOT NULL PRIMARY KEY,
+		PricePerServing int NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS article (
+		ArticleID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		Title varchar(255) NOT NULL,
+		Summary varchar(500) NOT NULL,
+		Body mediumtext NOT NULL,
+		Lang char(2) NOT NULL,
+		AuthorID char(5) NOT NULL,
+		Status varchar(10) NOT NULL,
+		CreatedAt datetime NOT NULL,
+		UpdatedAt datetime NOT NULL,
+		PublishedAt datetime NULL,
+		INDEX (Status, PublishedAt),
+		FULLTEXT (Title, Summary, Body)
+	)`,
+	`CREATE TABLE IF NOT EXISTS article_label (
+		ArticleID int NOT NULL,
+		Kind varchar(10) NOT NULL,
+		Value varchar(30) NOT NULL,
+		PRIMARY KEY (ArticleID, Kind, Value),
+		INDEX (Kind, Value)
+	)`,
+	`CREATE TABLE IF NOT EXISTS article_bookmark (
+		UserID char(5) NOT NULL,
+		LinkHash char(64) NOT NULL,
+		Link varchar(2048) NOT NULL,
+		Title varchar(255) NOT NULL,
+		Snippet varchar(1000) NOT NULL,
+		RemindAt datetime NULL,
+		CreatedAt datetime NOT NULL,
+		UpdatedAt datetime NOT NULL,
+		PRIMARY KEY (UserID, LinkHash),
+		INDEX (UserID, RemindAt)
+	)`,
+	`CREATE TABLE IF NOT EXISTS article_read (
+		ReadID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		UserID char(5) NOT NULL,
+		LinkHash char(64) NOT NULL,
+		Link varchar(2048) NOT NULL,
+		Title varchar(255) NOT NULL,
+		ReadAt datetime NOT NULL,
+		INDEX (UserID, ReadAt)
+	)`,
+	`CREATE TABLE IF NOT EXISTS calorie_requirement (
+		RequirementID int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		UserID char(5) NOT NULL,
+		Calories float NOT NULL,
+		CalculatedAt datetime NOT NULL,
+		INDEX (UserID, RequirementID)
+	)`,
+	`CREATE TABLE IF NOT EXISTS meal_detail_quantity (
+		TrackID char(5) NOT NULL,
+		FoodID char(5) NOT NULL,
+		Quantity float NOT NULL,
+		PRIMARY KEY (TrackID, FoodID)
+	)`,
+}
+
+func migrate(db *sql.DB) error {
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error migrating database: %v", err)
+		}
+	}
+	return nil
+}
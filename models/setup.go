@@ -23,6 +23,11 @@ func Setup() error {
 		return fmt.Errorf("error verifying connection to the database: %v", err)
 	}
 
+	err = migrate(db)
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("Successfully connected to the database")
 	return nil
 }
@@ -30,3 +35,8 @@ func Setup() error {
 func GetDB() *sql.DB {
 	return db
 }
+
+// SetDB replaces the shared database, e.g. with a dbtest database.
+func SetDB(d *sql.DB) {
+	db = d
+}
@@ -0,0 +1,312 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nutrishe/entity"
+)
+
+// Supermarket aisles a shopping list is grouped by, in walking order.
+const (
+	AisleProduce   = "produce"
+	AisleMeatFish  = "meat_fish"
+	AisleDairyEggs = "dairy_eggs"
+	AisleGrains    = "grains_bakery"
+	AisleSpices    = "spices_condiments"
+	AislePantry    = "pantry"
+	AisleBeverages = "beverages"
+	AisleOther     = "other"
+)
+
+var aisleOrder = []string{AisleProduce, AisleMeatFish, AisleDairyEggs, AisleGrains, AisleSpices, AislePantry, AisleBeverages, AisleOther}
+
+// aisleKeywords are words (English and Indonesian) that place an ingredient
+// in an aisle when the plan does not say.
+var aisleKeywords = map[string][]string{
+	AisleProduce:   {"vegetable", "fruit", "tomato", "onion", "garlic", "carrot", "spinach", "cabbage", "potato", "banana", "apple", "lime", "lemon", "chili", "bawang", "sayur", "bayam", "kangkung", "wortel", "kentang", "tomat", "cabai", "cabe", "pisang", "apel", "jeruk", "kol", "tauge", "timun", "jagung"},
+	AisleMeatFish:  {"chicken", "beef", "fish", "shrimp", "prawn", "meat", "tuna", "salmon", "ayam", "daging", "sapi", "ikan", "udang", "cumi"},
+	AisleDairyEggs: {"milk", "cheese", "yogurt", "butter", "egg", "cream", "susu", "keju", "telur", "mentega"},
+	AisleGrains:    {"rice", "bread", "noodle", "pasta", "oat", "flour", "tortilla", "nasi", "beras", "roti", "mie", "bihun", "tepung"},
+	AisleSpices:    {"salt", "pepper", "sauce", "soy", "kecap", "sambal", "spice", "turmeric", "ginger", "garam", "merica", "lada", "kunyit", "jahe", "lengkuas", "sereh", "saus", "terasi", "ketumbar"},
+	AislePantry:    {"oil", "sugar", "tofu", "tempe", "tempeh", "bean", "lentil", "nut", "coconut milk", "minyak", "gula", "tahu", "kacang", "santan"},
+	AisleBeverages: {"tea", "coffee", "juice", "water", "teh", "kopi", "jus", "air"},
+}
+
+// IsAisle reports whether aisle is one of the known aisles.
+func IsAisle(aisle string) bool {
+	for _, a := range aisleOrder {
+		if a == aisle {
+			return true
+		}
+	}
+	return false
+}
+
+// CategorizeIngredient guesses the aisle of an ingredient from its name.
+func CategorizeIngredient(name string) string {
+	words := strings.Fields(normalizeName(name))
+	for _, aisle := range aisleOrder {
+		for _, keyword := range aisleKeywords[aisle] {
+			for _, word := range words {
+				if word == keyword || strings.TrimSuffix(word, "s") == keyword {
+					return aisle
+				}
+			}
+			// Keywords of several words, like "coconut milk"
+			if strings.Contains(keyword, " ") && strings.Contains(strings.Join(words, " "), keyword) {
+				return aisle
+			}
+		}
+	}
+	return AisleOther
+}
+
+// parsePortion reads a portion such as "1 bowl" or "150 g" into a
+// quantity and unit, defaulting to one serving.
+func parsePortion(portion string) (float64, string) {
+	words := strings.Fields(portion)
+	if len(words) == 0 {
+		return 1, "serving"
+	}
+	quantity, ok := parseQuantity(words[0])
+	if !ok {
+		return 1, "serving"
+	}
+	if len(words) > 1 {
+		if unit, ok := mealUnits[strings.ToLower(words[1])]; ok {
+			return quantity, unit
+		}
+	}
+	return quantity, "serving"
+}
+
+// PortionServings estimates how many servings of a catalogue food, whose
+// serving weighs servingGrams, a portion such as "2 pieces" or "150 g" is.
+func PortionServings(portion string, servingGrams int) float64 {
+	quantity, unit := parsePortion(portion)
+	return ParsedFood{Quantity: quantity, Unit: unit}.Servings(servingGrams)
+}
+
+// itemIngredients returns the ingredients of an item, or the item itself
+// when the plan lists none.
+func itemIngredients(item entity.MealItem) []entity.Ingredient {
+	if len(item.Ingredients) > 0 {
+		return item.Ingredients
+	}
+	quantity, unit := parsePortion(item.Portion)
+	return []entity.Ingredient{{Name: item.Name, Quantity: quantity, Unit: unit}}
+}
+
+// PlanIngredients lists the ingredients of every item in a plan.
+func PlanIngredients(plan *entity.MealPlan) []entity.Ingredient {
+	var ingredients []entity.Ingredient
+	for _, day := range plan.Days {
+		for _, meal := range day.Meals {
+			for _, item := range meal.Items {
+				ingredients = append(ingredients, itemIngredients(item)...)
+			}
+		}
+	}
+	return ingredients
+}
+
+// ShoppingItem is an ingredient to buy, summed over the whole plan.
+type ShoppingItem struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	// PantryNote says when a matching pantry item could not be subtracted
+	// because its unit cannot be converted.
+	PantryNote string `json:"pantry_note,omitempty"`
+}
+
+// ShoppingAisle groups the items of a shopping list.
+type ShoppingAisle struct {
+	Aisle string         `json:"aisle"`
+	Items []ShoppingItem `json:"items"`
+}
+
+// PantryItem is something the user already has. A zero Quantity means
+// enough of it, so it is left off the list altogether.
+type PantryItem struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// unitScales converts mass and volume units into grams and millilitres, so
+// that "1 kg" and "500 g" of the same ingredient add up.
+var unitScales = map[string]struct {
+	unit  string
+	scale float64
+}{
+	"g": {"g", 1}, "gr": {"g", 1}, "gram": {"g", 1}, "grams": {"g", 1},
+	"kg": {"g", 1000}, "kilogram": {"g", 1000}, "kilograms": {"g", 1000},
+	"mg": {"g", 0.001},
+	"ml": {"ml", 1}, "milliliter": {"ml", 1}, "millilitre": {"ml", 1},
+	"l": {"ml", 1000}, "liter": {"ml", 1000}, "litre": {"ml", 1000}, "liters": {"ml", 1000}, "litres": {"ml", 1000},
+}
+
+// normalizeUnit converts a quantity into grams or millilitres when its unit
+// is a mass or volume, and otherwise only cleans up the unit.
+func normalizeUnit(quantity float64, unit string) (float64, string) {
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	if u, ok := unitScales[unit]; ok {
+		return quantity * u.scale, u.unit
+	}
+	return quantity, unit
+}
+
+// pantryMatchThreshold is how similar an ingredient name must be to a
+// pantry item to count as the same thing.
+const pantryMatchThreshold = 0.8
+
+// BuildShoppingList sums the ingredients by name and unit, subtracts what is
+// in the pantry and groups the rest by aisle. Mass and volume units are
+// converted to grams and millilitres first. A pantry item is used up across
+// the ingredients it matches, closest name first, so it is never subtracted
+// twice.
+func BuildShoppingList(ingredients []entity.Ingredient, pantry []PantryItem) []ShoppingAisle {
+	type key struct{ name, unit string }
+	totals := make(map[key]*ShoppingItem)
+	aisles := make(map[key]string)
+	var order []key
+
+	for _, ing := range ingredients {
+		name := strings.TrimSpace(ing.Name)
+		if name == "" || ing.Quantity <= 0 {
+			continue
+		}
+		quantity, unit := normalizeUnit(ing.Quantity, ing.Unit)
+		k := key{normalizeName(name), unit}
+		if item, ok := totals[k]; ok {
+			item.Quantity += quantity
+			continue
+		}
+
+		totals[k] = &ShoppingItem{Name: name, Quantity: quantity, Unit: unit}
+		aisle := strings.ToLower(ing.Category)
+		if !IsAisle(aisle) || aisle == AisleOther {
+			aisle = CategorizeIngredient(name)
+		}
+		aisles[k] = aisle
+		order = append(order, k)
+	}
+
+	for _, p := range pantry {
+		matches := make([]key, 0)
+		similarity := make(map[key]float64)
+		for _, k := range order {
+			if score := NameSimilarity(totals[k].Name, p.Name); score >= pantryMatchThreshold {
+				matches = append(matches, k)
+				similarity[k] = score
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			return similarity[matches[i]] > similarity[matches[j]]
+		})
+
+		remaining, unit := normalizeUnit(p.Quantity, p.Unit)
+		for _, k := range matches {
+			item := totals[k]
+			switch {
+			case item.Quantity <= 0:
+			case p.Quantity <= 0:
+				item.Quantity = 0
+			case unit != item.Unit:
+				amount := strings.TrimSpace(strconv.FormatFloat(p.Quantity, 'f', -1, 64) + " " + p.Unit)
+				item.PantryNote = fmt.Sprintf("nothing subtracted for %s of %s in the pantry, its unit does not match", amount, p.Name)
+			case remaining > 0:
+				used := math.Min(remaining, item.Quantity)
+				item.Quantity -= used
+				remaining -= used
+			}
+		}
+	}
+
+	grouped := make(map[string][]ShoppingItem)
+	for _, k := range order {
+		item := totals[k]
+		if item.Quantity <= 0 {
+			continue
+		}
+		item.Quantity = formatFloat(item.Quantity, 2)
+		grouped[aisles[k]] = append(grouped[aisles[k]], *item)
+	}
+
+	list := []ShoppingAisle{}
+	for _, aisle := range aisleOrder {
+		if items, ok := grouped[aisle]; ok {
+			list = append(list, ShoppingAisle{Aisle: aisle, Items: items})
+		}
+	}
+	return list
+}
+
+// ShoppingListText formats a shopping list as plain text, one aisle per
+// heading.
+func ShoppingListText(list []ShoppingAisle) string {
+	var b strings.Builder
+	for i, aisle := range list {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		title := strings.ReplaceAll(aisle.Aisle, "_", " & ")
+		fmt.Fprintf(&b, "%s\n", strings.ToUpper(title[:1])+title[1:])
+		for _, item := range aisle.Items {
+			fmt.Fprintf(&b, "- %s %s %s", strconv.FormatFloat(item.Quantity, 'f', -1, 64), item.Unit, item.Name)
+			if item.PantryNote != "" {
+				fmt.Fprintf(&b, " (%s)", item.PantryNote)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func GetPantry(db *sql.DB, userID string) ([]PantryItem, error) {
+	rows, err := db.Query("SELECT Name, Quantity, Unit FROM pantry_item WHERE UserID = ? ORDER BY Name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []PantryItem{}
+	for rows.Next() {
+		var item PantryItem
+		if err := rows.Scan(&item.Name, &item.Quantity, &item.Unit); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SavePantry replaces the pantry of a user.
+func SavePantry(db *sql.DB, userID string, items []PantryItem) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM pantry_item WHERE UserID = ?", userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, item := range items {
+		_, err = tx.Exec("REPLACE INTO pantry_item (UserID, Name, Quantity, Unit) VALUES (?, ?, ?, ?)", userID, item.Name, item.Quantity, item.Unit)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
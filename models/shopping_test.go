@@ -0,0 +1,101 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"nutrishe/entity"
+)
+
+func TestBuildShoppingList(t *testing.T) {
+	tests := []struct {
+		name        string
+		ingredients []entity.Ingredient
+		pantry      []PantryItem
+		want        []ShoppingAisle
+	}{
+		{
+			name: "same ingredient is summed across units",
+			ingredients: []entity.Ingredient{
+				{Name: "Beras", Quantity: 1, Unit: "kg"},
+				{Name: "beras", Quantity: 500, Unit: "g"},
+				{Name: "Bayam", Quantity: 1, Unit: "bunch", Category: "produce"},
+			},
+			want: []ShoppingAisle{
+				{Aisle: AisleProduce, Items: []ShoppingItem{{Name: "Bayam", Quantity: 1, Unit: "bunch"}}},
+				{Aisle: AisleGrains, Items: []ShoppingItem{{Name: "Beras", Quantity: 1500, Unit: "g"}}},
+			},
+		},
+		{
+			name: "pantry is used up once across similar names",
+			ingredients: []entity.Ingredient{
+				{Name: "chicken breast", Quantity: 300, Unit: "g"},
+				{Name: "chicken breasts", Quantity: 400, Unit: "g"},
+			},
+			pantry: []PantryItem{{Name: "chicken breast", Quantity: 500, Unit: "g"}},
+			want: []ShoppingAisle{
+				{Aisle: AisleMeatFish, Items: []ShoppingItem{{Name: "chicken breasts", Quantity: 200, Unit: "g"}}},
+			},
+		},
+		{
+			name:        "pantry units are converted",
+			ingredients: []entity.Ingredient{{Name: "susu", Quantity: 1500, Unit: "ml"}},
+			pantry:      []PantryItem{{Name: "Susu", Quantity: 1, Unit: "L"}},
+			want: []ShoppingAisle{
+				{Aisle: AisleDairyEggs, Items: []ShoppingItem{{Name: "susu", Quantity: 500, Unit: "ml"}}},
+			},
+		},
+		{
+			name:        "pantry without a quantity covers the ingredient",
+			ingredients: []entity.Ingredient{{Name: "garam", Quantity: 5, Unit: "g"}},
+			pantry:      []PantryItem{{Name: "garam"}},
+			want:        []ShoppingAisle{},
+		},
+		{
+			name:        "unconvertible pantry unit is reported",
+			ingredients: []entity.Ingredient{{Name: "telur", Quantity: 4, Unit: "piece"}},
+			pantry:      []PantryItem{{Name: "telur", Quantity: 250, Unit: "g"}},
+			want: []ShoppingAisle{
+				{Aisle: AisleDairyEggs, Items: []ShoppingItem{{Name: "telur", Quantity: 4, Unit: "piece", PantryNote: "nothing subtracted for 250 g of telur in the pantry, its unit does not match"}}},
+			},
+		},
+		{
+			name:        "empty and zero ingredients are skipped",
+			ingredients: []entity.Ingredient{{Name: " ", Quantity: 1}, {Name: "teh", Quantity: 0}},
+			want:        []ShoppingAisle{},
+		},
+	}
+	for _, tt := range tests {
+		if got := BuildShoppingList(tt.ingredients, tt.pantry); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: BuildShoppingList = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShoppingListText(t *testing.T) {
+	list := []ShoppingAisle{
+		{Aisle: AisleMeatFish, Items: []ShoppingItem{{Name: "ayam", Quantity: 0.5, Unit: "g", PantryNote: "note"}}},
+		{Aisle: AisleProduce, Items: []ShoppingItem{{Name: "bayam", Quantity: 2, Unit: "bunch"}}},
+	}
+	want := "Meat & fish\n- 0.5 g ayam (note)\n\nProduce\n- 2 bunch bayam\n"
+	if got := ShoppingListText(list); got != want {
+		t.Errorf("ShoppingListText = %q, want %q", got, want)
+	}
+}
+
+func TestCategorizeIngredient(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Tomat merah", AisleProduce},
+		{"Daging sapi", AisleMeatFish},
+		{"eggs", AisleDairyEggs},
+		{"Santan", AislePantry},
+		{"unobtainium", AisleOther},
+	}
+	for _, tt := range tests {
+		if got := CategorizeIngredient(tt.name); got != tt.want {
+			t.Errorf("CategorizeIngredient(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Summary bucket sizes accepted by SummarizeIntake.
+const (
+	BucketDaily   = "daily"
+	BucketWeekly  = "weekly"
+	BucketMonthly = "monthly"
+)
+
+// goalTolerance is how far (as a fraction of the goal) a day's intake may
+// be from the calorie goal and still count as on target.
+const goalTolerance = 0.10
+
+// DailyIntake is what a user logged on a single day.
+type DailyIntake struct {
+	Date          time.Time
+	TotalCalories int
+	Protein       float64
+	Carbohydrates float64
+	Fat           float64
+	Fiber         float64
+}
+
+// SummaryBucket aggregates the logged days between Start and End.
+type SummaryBucket struct {
+	Start            string  `json:"start"`
+	End              string  `json:"end"`
+	DaysLogged       int     `json:"days_logged"`
+	TotalCalories    int     `json:"total_calories"`
+	AverageCalories  float64 `json:"average_calories"`
+	MinCalories      int     `json:"min_calories"`
+	MaxCalories      int     `json:"max_calories"`
+	Protein          float64 `json:"protein"`
+	Carbohydrates    float64 `json:"carbohydrates"`
+	Fat              float64 `json:"fat"`
+	Fiber            float64 `json:"fiber"`
+	DaysWithGoal     int     `json:"days_with_goal"`
+	DaysOnTarget     int     `json:"days_on_target"`
+	AdherencePercent float64 `json:"adherence_percent"`
+}
+
+// NutritionSummary is the result of SummarizeIntake.
+type NutritionSummary struct {
+	UserID  string          `json:"user_id"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Bucket  string          `json:"bucket"`
+	Overall SummaryBucket   `json:"overall"`
+	Buckets []SummaryBucket `json:"buckets"`
+}
+
+// parseDBDate parses DATE and DATETIME values, which the driver returns as
+// strings because the DSN does not enable parseTime.
+func parseDBDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date value: %q", value)
+}
+
+// GetDailyIntake returns the calories and macros logged per day in the
+// inclusive date range.
+func GetDailyIntake(db *sql.DB, userID string, from, to time.Time) ([]DailyIntake, error) {
+	query := `SELECT dm.MealDate, dm.TotalCalories,
+	                 COALESCE(SUM(f.Protein * COALESCE(q.Quantity, 1)), 0), COALESCE(SUM(f.Carbohydrates * COALESCE(q.Quantity, 1)), 0),
+	                 COALESCE(SUM(f.Fat * COALESCE(q.Quantity, 1)), 0), COALESCE(SUM(f.Fiber * COALESCE(q.Quantity, 1)), 0)
+	          FROM daily_meal dm
+	          LEFT JOIN meal_detail md ON md.TrackID = dm.TrackID
+	          LEFT JOIN meal_detail_quantity q ON q.TrackID = md.TrackID AND q.FoodID = md.FoodID
+	          LEFT JOIN food f ON f.FoodID = md.FoodID
+	          WHERE dm.UserID = ? AND dm.MealDate BETWEEN ? AND ?
+	          GROUP BY dm.TrackID, dm.MealDate, dm.TotalCalories
+	          ORDER BY dm.MealDate`
+	rows, err := db.Query(query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []DailyIntake
+	for rows.Next() {
+		var day DailyIntake
+		var mealDate string
+		if err := rows.Scan(&mealDate, &day.TotalCalories, &day.Protein, &day.Carbohydrates, &day.Fat, &day.Fiber); err != nil {
+			return nil, err
+		}
+		day.Date, err = parseDBDate(mealDate)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// GetDietPlansInRange returns the user's diet plans overlapping the range.
+func GetDietPlansInRange(db *sql.DB, userID string, from, to time.Time) ([]DietPlan, error) {
+	query := "SELECT PlanID, UserID, StartDate, EndDate, CalorieGoal FROM diet_plan WHERE UserID = ? AND StartDate <= ? AND EndDate >= ? ORDER BY StartDate"
+	rows, err := db.Query(query, userID, to.Format("2006-01-02"), from.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []DietPlan
+	for rows.Next() {
+		plan, err := scanDietPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// goalForDay returns the calorie goal of the plan covering day, or 0.
+func goalForDay(plans []DietPlan, day time.Time) int {
+	for _, plan := range plans {
+		if !day.Before(truncateDay(plan.StartDate)) && !day.After(truncateDay(plan.EndDate)) {
+			return plan.CalorieGoal
+		}
+	}
+	return 0
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// IsOnTarget reports whether calories are within the tolerance of goal.
+func IsOnTarget(calories, goal int) bool {
+	if goal <= 0 {
+		return false
+	}
+	return math.Abs(float64(calories-goal)) <= float64(goal)*goalTolerance
+}
+
+func bucketStart(day time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeekly:
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case BucketMonthly:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func bucketEnd(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeekly:
+		return start.AddDate(0, 0, 6)
+	case BucketMonthly:
+		return start.AddDate(0, 1, -1)
+	default:
+		return start
+	}
+}
+
+func (b *SummaryBucket) add(day DailyIntake, goal int) {
+	if b.DaysLogged == 0 || day.TotalCalories < b.MinCalories {
+		b.MinCalories = day.TotalCalories
+	}
+	if day.TotalCalories > b.MaxCalories {
+		b.MaxCalories = day.TotalCalories
+	}
+	b.DaysLogged++
+	b.TotalCalories += day.TotalCalories
+	b.Protein += day.Protein
+	b.Carbohydrates += day.Carbohydrates
+	b.Fat += day.Fat
+	b.Fiber += day.Fiber
+
+	if goal > 0 {
+		b.DaysWithGoal++
+		if IsOnTarget(day.TotalCalories, goal) {
+			b.DaysOnTarget++
+		}
+	}
+}
+
+func (b *SummaryBucket) finish() {
+	if b.DaysLogged > 0 {
+		b.AverageCalories = formatFloat(float64(b.TotalCalories)/float64(b.DaysLogged), 2)
+	}
+	if b.DaysWithGoal > 0 {
+		b.AdherencePercent = formatFloat(float64(b.DaysOnTarget)*100/float64(b.DaysWithGoal), 2)
+	}
+	b.Protein = formatFloat(b.Protein, 2)
+	b.Carbohydrates = formatFloat(b.Carbohydrates, 2)
+	b.Fat = formatFloat(b.Fat, 2)
+	b.Fiber = formatFloat(b.Fiber, 2)
+}
+
+// SummarizeIntake groups the logged days into daily, weekly or monthly
+// buckets, comparing each day with the diet plan active on that day.
+// Buckets without logged days are still returned so the range has no gaps.
+func SummarizeIntake(userID string, days []DailyIntake, plans []DietPlan, bucket string, from, to time.Time) NutritionSummary {
+	from, to = truncateDay(from), truncateDay(to)
+
+	summary := NutritionSummary{
+		UserID:  userID,
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Bucket:  bucket,
+		Overall: SummaryBucket{Start: from.Format("2006-01-02"), End: to.Format("2006-01-02")},
+		Buckets: []SummaryBucket{},
+	}
+
+	index := make(map[string]int)
+	for start := bucketStart(from, bucket); !start.After(to); start = bucketEnd(start, bucket).AddDate(0, 0, 1) {
+		key := start.Format("2006-01-02")
+		index[key] = len(summary.Buckets)
+		summary.Buckets = append(summary.Buckets, SummaryBucket{Start: key, End: bucketEnd(start, bucket).Format("2006-01-02")})
+	}
+
+	for _, day := range days {
+		date := truncateDay(day.Date)
+		goal := goalForDay(plans, date)
+		summary.Overall.add(day, goal)
+		if i, ok := index[bucketStart(date, bucket).Format("2006-01-02")]; ok {
+			summary.Buckets[i].add(day, goal)
+		}
+	}
+
+	summary.Overall.finish()
+	for i := range summary.Buckets {
+		summary.Buckets[i].finish()
+	}
+	return summary
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestIsOnTarget(t *testing.T) {
+	tests := []struct {
+		calories, goal int
+		want           bool
+	}{
+		{1800, 1800, true},
+		{1620, 1800, true},
+		{1980, 1800, true},
+		{1619, 1800, false},
+		{1981, 1800, false},
+		{1800, 0, false},
+	}
+	for _, tt := range tests {
+		if got := IsOnTarget(tt.calories, tt.goal); got != tt.want {
+			t.Errorf("IsOnTarget(%d, %d) = %v, want %v", tt.calories, tt.goal, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeIntake(t *testing.T) {
+	days := []DailyIntake{
+		{Date: date("2024-03-04"), TotalCalories: 1800, Protein: 60, Fiber: 20},
+		{Date: date("2024-03-05"), TotalCalories: 2400, Protein: 70, Fiber: 10},
+		{Date: date("2024-03-12"), TotalCalories: 1500, Protein: 50, Fiber: 30},
+	}
+	// The plan only covers the first week
+	plans := []DietPlan{{PlanID: "DP001", StartDate: date("2024-03-01"), EndDate: date("2024-03-10"), CalorieGoal: 1800}}
+
+	tests := []struct {
+		bucket      string
+		from, to    string
+		wantBuckets []SummaryBucket
+	}{
+		{
+			bucket: BucketWeekly,
+			from:   "2024-03-06", to: "2024-03-17",
+			wantBuckets: []SummaryBucket{
+				{Start: "2024-03-04", End: "2024-03-10", DaysLogged: 2, TotalCalories: 4200, AverageCalories: 2100, MinCalories: 1800, MaxCalories: 2400, DaysWithGoal: 2, DaysOnTarget: 1, AdherencePercent: 50},
+				{Start: "2024-03-11", End: "2024-03-17", DaysLogged: 1, TotalCalories: 1500, AverageCalories: 1500, MinCalories: 1500, MaxCalories: 1500},
+			},
+		},
+		{
+			bucket: BucketMonthly,
+			from:   "2024-02-20", to: "2024-03-31",
+			wantBuckets: []SummaryBucket{
+				{Start: "2024-02-01", End: "2024-02-29"},
+				{Start: "2024-03-01", End: "2024-03-31", DaysLogged: 3, TotalCalories: 5700, AverageCalories: 1900, MinCalories: 1500, MaxCalories: 2400, DaysWithGoal: 2, DaysOnTarget: 1, AdherencePercent: 50},
+			},
+		},
+		{
+			bucket: BucketDaily,
+			from:   "2024-03-04", to: "2024-03-06",
+			wantBuckets: []SummaryBucket{
+				{Start: "2024-03-04", End: "2024-03-04", DaysLogged: 1, TotalCalories: 1800, AverageCalories: 1800, MinCalories: 1800, MaxCalories: 1800, DaysWithGoal: 1, DaysOnTarget: 1, AdherencePercent: 100},
+				{Start: "2024-03-05", End: "2024-03-05", DaysLogged: 1, TotalCalories: 2400, AverageCalories: 2400, MinCalories: 2400, MaxCalories: 2400, DaysWithGoal: 1},
+				{Start: "2024-03-06", End: "2024-03-06"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		summary := SummarizeIntake("US001", days, plans, tt.bucket, date(tt.from), date(tt.to))
+		if len(summary.Buckets) != len(tt.wantBuckets) {
+			t.Fatalf("%s: got %d buckets, want %d: %+v", tt.bucket, len(summary.Buckets), len(tt.wantBuckets), summary.Buckets)
+		}
+		for i, want := range tt.wantBuckets {
+			got := summary.Buckets[i]
+			got.Protein, got.Carbohydrates, got.Fat, got.Fiber = 0, 0, 0, 0
+			if got != want {
+				t.Errorf("%s bucket %d = %+v, want %+v", tt.bucket, i, got, want)
+			}
+		}
+	}
+}
+
+func TestSummarizeIntakeOverall(t *testing.T) {
+	days := []DailyIntake{
+		{Date: date("2024-03-04"), TotalCalories: 1000, Protein: 10.123, Fiber: 5},
+		{Date: date("2024-03-05"), TotalCalories: 2001, Protein: 20.5, Fiber: 7.5},
+	}
+	summary := SummarizeIntake("US001", days, nil, BucketDaily, date("2024-03-04"), date("2024-03-05"))
+	overall := summary.Overall
+	if overall.AverageCalories != 1500.5 || overall.Protein != 30.62 || overall.Fiber != 12.5 || overall.DaysWithGoal != 0 || overall.AdherencePercent != 0 {
+		t.Errorf("Overall = %+v", overall)
+	}
+	if summary.From != "2024-03-04" || summary.To != "2024-03-05" {
+		t.Errorf("range = %s..%s", summary.From, summary.To)
+	}
+}
@@ -0,0 +1,219 @@
+package nutrition
+
+// Facts holds the nutrients of one serving of a food. Nil values are
+// unknown and are scored as zero.
+type Facts struct {
+	ServingGrams int
+	Calories     int
+	Protein      *float64
+	Fiber        *float64
+	Sugar        *float64
+	SaturatedFat *float64
+	Sodium       *int
+	Calcium      *int
+	Iron         *float64
+}
+
+// Scaled returns the facts of n servings, e.g. to score a day in which a
+// food was eaten more than once.
+func (f Facts) Scaled(n float64) Facts {
+	scaleFloat := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		scaled := *v * n
+		return &scaled
+	}
+	scaleInt := func(v *int) *int {
+		if v == nil {
+			return nil
+		}
+		scaled := int(float64(*v)*n + 0.5)
+		return &scaled
+	}
+	return Facts{
+		ServingGrams: int(float64(f.ServingGrams)*n + 0.5),
+		Calories:     int(float64(f.Calories)*n + 0.5),
+		Protein:      scaleFloat(f.Protein),
+		Fiber:        scaleFloat(f.Fiber),
+		Sugar:        scaleFloat(f.Sugar),
+		SaturatedFat: scaleFloat(f.SaturatedFat),
+		Sodium:       scaleInt(f.Sodium),
+		Calcium:      scaleInt(f.Calcium),
+		Iron:         scaleFloat(f.Iron),
+	}
+}
+
+// FoodScore is a Nutri-Score like grade of a single food, computed per
+// 100 g. Lower points are better; Partial is set when sugar, saturated fat
+// or sodium are unknown, which makes the grade optimistic.
+type FoodScore struct {
+	Points  int    `json:"points"`
+	Grade   string `json:"grade"`
+	Partial bool   `json:"partial"`
+}
+
+// DayScore is a diet quality index from 0 to 100 built from five equally
+// weighted components of 20 points each.
+type DayScore struct {
+	Index      int            `json:"index"`
+	Rating     string         `json:"rating"`
+	Components map[string]int `json:"components"`
+}
+
+// Daily reference values used by ScoreDay.
+const (
+	FiberTarget   = 25.0   // g
+	ProteinTarget = 50.0   // g
+	CalciumTarget = 1000.0 // mg
+	IronTarget    = 18.0   // mg
+	SugarLimit    = 50.0   // g, half of it scores full points
+	SatFatShare   = 0.10   // share of energy from saturated fat
+)
+
+var (
+	energyThresholds = []float64{335, 670, 1005, 1340, 1675, 2010, 2345, 2680, 3015, 3350}
+	sugarThresholds  = []float64{4.5, 9, 13.5, 18, 22.5, 27, 31, 36, 40, 45}
+	satFatThresholds = []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	sodiumThresholds = []float64{90, 180, 270, 360, 450, 540, 630, 720, 810, 900}
+	fiberThresholds  = []float64{0.9, 1.9, 2.8, 3.7, 4.7}
+	proteinThreshold = []float64{1.6, 3.2, 4.8, 6.4, 8.0}
+)
+
+func points(value float64, thresholds []float64) int {
+	p := 0
+	for _, t := range thresholds {
+		if value > t {
+			p++
+		}
+	}
+	return p
+}
+
+func value(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func intValue(v *int) float64 {
+	if v == nil {
+		return 0
+	}
+	return float64(*v)
+}
+
+// ScoreFood grades a food following the Nutri-Score algorithm for general
+// foods, without the fruit and vegetable component.
+func ScoreFood(f Facts) FoodScore {
+	scale := 1.0
+	if f.ServingGrams > 0 {
+		scale = 100 / float64(f.ServingGrams)
+	}
+
+	kJ := float64(f.Calories) * 4.184 * scale
+	negative := points(kJ, energyThresholds) +
+		points(value(f.Sugar)*scale, sugarThresholds) +
+		points(value(f.SaturatedFat)*scale, satFatThresholds) +
+		points(intValue(f.Sodium)*scale, sodiumThresholds)
+
+	fiber := points(value(f.Fiber)*scale, fiberThresholds)
+	protein := points(value(f.Protein)*scale, proteinThreshold)
+
+	score := negative - fiber
+	// Protein only counts for foods that are not already high in negatives
+	if negative < 11 {
+		score -= protein
+	}
+
+	return FoodScore{
+		Points:  score,
+		Grade:   grade(score),
+		Partial: f.Sugar == nil || f.SaturatedFat == nil || f.Sodium == nil,
+	}
+}
+
+func grade(score int) string {
+	switch {
+	case score <= -1:
+		return "A"
+	case score <= 2:
+		return "B"
+	case score <= 10:
+		return "C"
+	case score <= 18:
+		return "D"
+	default:
+		return "E"
+	}
+}
+
+func ratio(v, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	r := v / target
+	if r > 1 {
+		return 1
+	}
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+// ScoreDay rates a day of meals on fiber, protein, micronutrients (calcium
+// and iron), sugar and saturated fat.
+func ScoreDay(foods []Facts) DayScore {
+	var calories, protein, fiber, sugar, satFat, calcium, iron float64
+	for _, f := range foods {
+		calories += float64(f.Calories)
+		protein += value(f.Protein)
+		fiber += value(f.Fiber)
+		sugar += value(f.Sugar)
+		satFat += value(f.SaturatedFat)
+		calcium += intValue(f.Calcium)
+		iron += value(f.Iron)
+	}
+
+	components := map[string]int{
+		"fiber":          int(ratio(fiber, FiberTarget)*20 + 0.5),
+		"protein":        int(ratio(protein, ProteinTarget)*20 + 0.5),
+		"micronutrients": int((ratio(calcium, CalciumTarget)+ratio(iron, IronTarget))/2*20 + 0.5),
+		// Full points up to half the limit, none at the limit
+		"sugar": int((1-ratio(sugar-SugarLimit/2, SugarLimit/2))*20 + 0.5),
+	}
+
+	satFatPoints := 20
+	if calories > 0 {
+		share := satFat * 9 / calories
+		satFatPoints = int((1-ratio(share-SatFatShare, SatFatShare))*20 + 0.5)
+	}
+	components["saturated_fat"] = satFatPoints
+
+	// An empty day has nothing to reward
+	if len(foods) == 0 {
+		for k := range components {
+			components[k] = 0
+		}
+	}
+
+	index := 0
+	for _, c := range components {
+		index += c
+	}
+
+	return DayScore{Index: index, Rating: rating(index), Components: components}
+}
+
+func rating(index int) string {
+	switch {
+	case index >= 80:
+		return "good"
+	case index >= 60:
+		return "fair"
+	default:
+		return "poor"
+	}
+}
@@ -0,0 +1,125 @@
+package nutrition
+
+import "testing"
+
+func f64(v float64) *float64 { return &v }
+func i(v int) *int           { return &v }
+
+func TestScoreFood(t *testing.T) {
+	tests := []struct {
+		name string
+		food Facts
+		want FoodScore
+	}{
+		{
+			name: "apple",
+			food: Facts{ServingGrams: 100, Calories: 52, Sugar: f64(10), SaturatedFat: f64(0), Sodium: i(1), Fiber: f64(2.4), Protein: f64(0.3)},
+			want: FoodScore{Points: 0, Grade: "B"},
+		},
+		{
+			name: "chicken breast counts protein",
+			food: Facts{ServingGrams: 100, Calories: 165, Sugar: f64(0), SaturatedFat: f64(1), Sodium: i(74), Protein: f64(31)},
+			want: FoodScore{Points: -3, Grade: "A"},
+		},
+		{
+			name: "small serving is scaled to 100 g and high negatives ignore protein",
+			food: Facts{ServingGrams: 50, Calories: 250, Sugar: f64(20), SaturatedFat: f64(5), Sodium: i(400), Protein: f64(10)},
+			want: FoodScore{Points: 31, Grade: "E"},
+		},
+		{
+			name: "unknown nutrients are partial",
+			food: Facts{ServingGrams: 100},
+			want: FoodScore{Points: 0, Grade: "B", Partial: true},
+		},
+		{
+			name: "missing serving is treated as 100 g",
+			food: Facts{Calories: 400, Sugar: f64(0), SaturatedFat: f64(0), Sodium: i(0)},
+			want: FoodScore{Points: 4, Grade: "C"},
+		},
+	}
+	for _, tt := range tests {
+		if got := ScoreFood(tt.food); got != tt.want {
+			t.Errorf("%s: ScoreFood() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		points int
+		want   string
+	}{
+		{-5, "A"}, {-1, "A"}, {0, "B"}, {2, "B"}, {3, "C"}, {10, "C"}, {11, "D"}, {18, "D"}, {19, "E"},
+	}
+	for _, tt := range tests {
+		if got := grade(tt.points); got != tt.want {
+			t.Errorf("grade(%d) = %q, want %q", tt.points, got, tt.want)
+		}
+	}
+}
+
+func TestScaled(t *testing.T) {
+	f := Facts{ServingGrams: 100, Calories: 150, Protein: f64(4), Sodium: i(45), Calcium: i(25)}
+	got := f.Scaled(2.5)
+	if got.ServingGrams != 250 || got.Calories != 375 || *got.Protein != 10 || *got.Sodium != 113 || *got.Calcium != 63 {
+		t.Errorf("Scaled(2.5) = %+v", got)
+	}
+	if got.Fiber != nil || got.Iron != nil {
+		t.Error("unknown nutrients became known")
+	}
+	if *f.Protein != 4 {
+		t.Error("Scaled changed the original facts")
+	}
+}
+
+func TestScoreDay(t *testing.T) {
+	tests := []struct {
+		name       string
+		foods      []Facts
+		wantIndex  int
+		wantRating string
+		want       map[string]int
+	}{
+		{
+			name:       "empty day",
+			wantIndex:  0,
+			wantRating: "poor",
+			want:       map[string]int{"fiber": 0, "protein": 0, "micronutrients": 0, "sugar": 0, "saturated_fat": 0},
+		},
+		{
+			name: "all targets met",
+			foods: []Facts{
+				{Calories: 1200, Protein: f64(35), Fiber: f64(20), Sugar: f64(10), SaturatedFat: f64(6), Calcium: i(600), Iron: f64(10)},
+				{Calories: 800, Protein: f64(25), Fiber: f64(10), Sugar: f64(10), SaturatedFat: f64(4), Calcium: i(400), Iron: f64(8)},
+			},
+			wantIndex:  100,
+			wantRating: "good",
+			want:       map[string]int{"fiber": 20, "protein": 20, "micronutrients": 20, "sugar": 20, "saturated_fat": 20},
+		},
+		{
+			name:       "half the targets",
+			foods:      []Facts{{Calories: 2000, Protein: f64(25), Fiber: f64(12.5), Sugar: f64(37.5), SaturatedFat: f64(10), Calcium: i(1000), Iron: f64(18)}},
+			wantIndex:  70,
+			wantRating: "fair",
+			want:       map[string]int{"fiber": 10, "protein": 10, "micronutrients": 20, "sugar": 10, "saturated_fat": 20},
+		},
+		{
+			name:       "sugar and saturated fat at their limits",
+			foods:      []Facts{{Calories: 1000, Protein: f64(25), Fiber: f64(12.5), Sugar: f64(50), SaturatedFat: f64(200.0 / 9), Calcium: i(500), Iron: f64(9)}},
+			wantIndex:  30,
+			wantRating: "poor",
+			want:       map[string]int{"fiber": 10, "protein": 10, "micronutrients": 10, "sugar": 0, "saturated_fat": 0},
+		},
+	}
+	for _, tt := range tests {
+		got := ScoreDay(tt.foods)
+		if got.Index != tt.wantIndex || got.Rating != tt.wantRating {
+			t.Errorf("%s: ScoreDay() = %d %s, want %d %s", tt.name, got.Index, got.Rating, tt.wantIndex, tt.wantRating)
+		}
+		for k, v := range tt.want {
+			if got.Components[k] != v {
+				t.Errorf("%s: component %s = %d, want %d", tt.name, k, got.Components[k], v)
+			}
+		}
+	}
+}
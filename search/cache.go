@@ -0,0 +1,88 @@
+package search
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cached answers repeated queries from memory for a while instead of
+// searching again, which saves the Google quota. Queries are compared after
+// normalising case and whitespace. At most maxCacheEntries queries are
+// kept; the oldest is dropped to make room.
+type Cached struct {
+	Searcher
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	page    Page
+	expires time.Time
+}
+
+const maxCacheEntries = 1000
+
+func NewCached(s Searcher, ttl time.Duration) *Cached {
+	return &Cached{Searcher: s, ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func cacheKey(q Query) string {
+	text := strings.Join(strings.Fields(strings.ToLower(q.Text)), " ")
+	return strings.Join([]string{text, q.Lang, strconv.Itoa(q.Limit), strconv.Itoa(q.Start)}, "\x00")
+}
+
+func (c *Cached) lookup(key string) (Page, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	return entry.page, ok
+}
+
+func (c *Cached) store(key string, p Page) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries on write before evicting live ones
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		var oldest string
+		for k, entry := range c.entries {
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = cacheEntry{page: p, expires: now.Add(c.ttl)}
+}
+
+// Search returns a copy of the cached results so callers may change them.
+func (c *Cached) Search(ctx context.Context, q Query) (Page, error) {
+	key := cacheKey(q)
+	if p, ok := c.lookup(key); ok {
+		p.Articles = append([]Article{}, p.Articles...)
+		return p, nil
+	}
+
+	p, err := c.Searcher.Search(ctx, q)
+	if err != nil {
+		return Page{}, err
+	}
+	c.store(key, Page{Articles: append([]Article{}, p.Articles...), More: p.More})
+	return p, nil
+}
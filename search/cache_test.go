@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// countingSearcher counts the searches that reach it.
+type countingSearcher struct {
+	Fake
+	calls int
+}
+
+func (c *countingSearcher) Search(ctx context.Context, q Query) (Page, error) {
+	c.calls++
+	return c.Fake.Search(ctx, q)
+}
+
+func TestCached(t *testing.T) {
+	inner := &countingSearcher{Fake: Fake{Articles: []Article{{Title: "bayam", Link: "a"}}}}
+	cached := NewCached(inner, time.Hour)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		query     Query
+		wantCalls int
+	}{
+		{"first search", Query{Text: "Bayam"}, 1},
+		{"same query", Query{Text: "bayam"}, 1},
+		{"case and spacing are ignored", Query{Text: "  BAYAM "}, 1},
+		{"another page", Query{Text: "bayam", Start: 10}, 2},
+		{"another language", Query{Text: "bayam", Lang: "en"}, 3},
+	}
+	for _, tt := range tests {
+		if _, err := cached.Search(ctx, tt.query); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if inner.calls != tt.wantCalls {
+			t.Errorf("%s: calls = %d, want %d", tt.name, inner.calls, tt.wantCalls)
+		}
+	}
+
+	// Callers may change the results without touching the cache
+	p, _ := cached.Search(ctx, Query{Text: "bayam"})
+	p.Articles[0].Title = "changed"
+	if p, _ = cached.Search(ctx, Query{Text: "bayam"}); p.Articles[0].Title != "bayam" {
+		t.Errorf("cached title = %q, want bayam", p.Articles[0].Title)
+	}
+
+	// Whether more results follow is cached with the page
+	more := &countingSearcher{Fake: Fake{Articles: []Article{{Title: "bayam 1"}, {Title: "bayam 2"}}}}
+	cachedMore := NewCached(more, time.Hour)
+	for i := 0; i < 2; i++ {
+		if p, _ := cachedMore.Search(ctx, Query{Text: "bayam", Limit: 1}); !p.More {
+			t.Errorf("search %d: More = false, want true", i+1)
+		}
+	}
+
+	// Expired entries are searched again
+	for k, entry := range cached.entries {
+		entry.expires = time.Now().Add(-time.Minute)
+		cached.entries[k] = entry
+	}
+	calls := inner.calls
+	cached.Search(ctx, Query{Text: "bayam"})
+	if inner.calls != calls+1 {
+		t.Errorf("expired entry was served from the cache")
+	}
+}
+
+func TestCachedSkipsErrors(t *testing.T) {
+	inner := &countingSearcher{Fake: Fake{Err: errors.New("quota exceeded")}}
+	cached := NewCached(inner, time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Search(context.Background(), Query{Text: "bayam"}); err == nil {
+			t.Fatal("error was not returned")
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("calls = %d, want 2", inner.calls)
+	}
+}
+
+func TestCachedBound(t *testing.T) {
+	cached := NewCached(&Fake{}, time.Hour)
+	for i := 0; i <= maxCacheEntries; i++ {
+		cached.store(strconv.Itoa(i), Page{})
+		// Keep the expiry times distinct so the oldest is well defined
+		entry := cached.entries[strconv.Itoa(i)]
+		entry.expires = entry.expires.Add(time.Duration(i) * time.Millisecond)
+		cached.entries[strconv.Itoa(i)] = entry
+	}
+	if len(cached.entries) != maxCacheEntries {
+		t.Errorf("entries = %d, want %d", len(cached.entries), maxCacheEntries)
+	}
+	if _, ok := cached.lookup("0"); ok {
+		t.Error("oldest entry was kept")
+	}
+	if _, ok := cached.lookup(strconv.Itoa(maxCacheEntries)); !ok {
+		t.Error("newest entry was dropped")
+	}
+}
@@ -0,0 +1,45 @@
+package search
+
+import (
+	"context"
+	"strings"
+)
+
+// Fake is a searcher for tests and offline development. It returns
+// Articles whose title or snippet contains a query word, or Err when set.
+type Fake struct {
+	Articles []Article
+	Err      error
+}
+
+func NewFake() *Fake {
+	return &Fake{Articles: []Article{
+		{Title: "Makanan kaya zat besi untuk saat menstruasi", Link: "https://example.com/zat-besi-menstruasi", Snippet: "Bayam, hati ayam dan kacang-kacangan membantu mengganti zat besi yang hilang."},
+		{Title: "Iron-rich foods during your period", Link: "https://example.com/iron-period", Snippet: "Spinach, lentils and lean meat help replace the iron lost during menstruation."},
+		{Title: "Tips diet sehat untuk mahasiswa", Link: "https://example.com/diet-mahasiswa", Snippet: "Tempe, tahu dan telur adalah sumber protein yang murah."},
+	}}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Search(ctx context.Context, q Query) (Page, error) {
+	if f.Err != nil {
+		return Page{}, f.Err
+	}
+
+	words := tokenize(q.Text)
+	articles := []Article{}
+	for _, a := range f.Articles {
+		text := strings.ToLower(a.Title + " " + a.Snippet)
+		for _, w := range words {
+			if strings.Contains(text, w) {
+				articles = append(articles, a)
+				break
+			}
+		}
+	}
+	articles, more := page(articles, q)
+	return Page{Articles: articles, More: more}, nil
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const googleURL = "https://www.googleapis.com/customsearch/v1"
+
+// Google searches with the Google Custom Search JSON API.
+type Google struct {
+	apiKey string
+	cx     string
+	client *http.Client
+}
+
+func NewGoogle(apiKey, cx string) *Google {
+	return &Google{apiKey: apiKey, cx: cx, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (g *Google) Name() string {
+	return "google"
+}
+
+// maxGoogleResults is how far Google Custom Search pages a query.
+const maxGoogleResults = 100
+
+type googleResult struct {
+	Items   []Article `json:"items"`
+	Queries struct {
+		// NextPage is only present when there are more results
+		NextPage []json.RawMessage `json:"nextPage"`
+	} `json:"queries"`
+}
+
+func (g *Google) Search(ctx context.Context, q Query) (Page, error) {
+	if g.apiKey == "" || g.cx == "" {
+		return Page{}, fmt.Errorf("google search is not configured")
+	}
+
+	params := url.Values{}
+	params.Set("key", g.apiKey)
+	params.Set("cx", g.cx)
+	params.Set("q", q.Text)
+	// The API returns at most 10 results per request
+	num := 10
+	if q.Limit > 0 && q.Limit < num {
+		num = q.Limit
+	}
+	// start is one-based and start + num may not exceed 100
+	start := q.Start + 1
+	if start+num > maxGoogleResults {
+		num = maxGoogleResults - start
+	}
+	if num <= 0 {
+		return Page{Articles: []Article{}}, nil
+	}
+	params.Set("num", strconv.Itoa(num))
+	if start > 1 {
+		params.Set("start", strconv.Itoa(start))
+	}
+	if q.Lang != "" {
+		params.Set("hl", q.Lang)
+		params.Set("lr", "lang_"+q.Lang)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, googleURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return Page{}, err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		// The request URL holds the API key, never let it into errors
+		if urlErr, ok := err.(*url.Error); ok {
+			urlErr.URL = googleURL
+		}
+		return Page{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Page{}, fmt.Errorf("google search failed with status %d: %s", resp.StatusCode, msg)
+	}
+
+	var result googleResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Page{}, err
+	}
+	if result.Items == nil {
+		result.Items = []Article{}
+	}
+	more := len(result.Queries.NextPage) > 0 && start+num < maxGoogleResults
+	return Page{Articles: result.Items, More: more}, nil
+}
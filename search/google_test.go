@@ -0,0 +1,102 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// recordTransport answers every request with body, or no results, and
+// keeps its query parameters.
+type recordTransport struct {
+	body   string
+	params []string
+}
+
+func (rt *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	q := req.URL.Query()
+	rt.params = append(rt.params, "num="+q.Get("num")+" start="+q.Get("start"))
+	body := rt.body
+	if body == "" {
+		body = `{}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+}
+
+func TestGooglePaging(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  string
+	}{
+		{"first page", Query{Limit: 10}, "num=10 start="},
+		{"default limit", Query{}, "num=10 start="},
+		{"limit above the API maximum", Query{Limit: 20}, "num=10 start="},
+		{"second page", Query{Limit: 10, Start: 10}, "num=10 start=11"},
+		{"last full page", Query{Limit: 10, Start: 89}, "num=10 start=90"},
+		{"near the end", Query{Limit: 10, Start: 90}, "num=9 start=91"},
+	}
+	for _, tt := range tests {
+		rt := &recordTransport{}
+		g := NewGoogle("key", "cx")
+		g.client = &http.Client{Transport: rt}
+		tt.query.Text = "bayam"
+		if _, err := g.Search(context.Background(), tt.query); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(rt.params) != 1 || rt.params[0] != tt.want {
+			t.Errorf("%s: requests = %v, want %q", tt.name, rt.params, tt.want)
+		}
+	}
+
+	// Nothing is left to ask for past the end
+	rt := &recordTransport{}
+	g := NewGoogle("key", "cx")
+	g.client = &http.Client{Transport: rt}
+	if p, err := g.Search(context.Background(), Query{Text: "bayam", Start: 99}); err != nil || len(p.Articles) != 0 || p.More || len(rt.params) != 0 {
+		t.Errorf("past the end: page = %+v, err = %v, requests = %v", p, err, rt.params)
+	}
+}
+
+func TestGoogleMore(t *testing.T) {
+	const withNext = `{"items":[{"title":"Bayam","link":"https://example.com/1"}],"queries":{"nextPage":[{"startIndex":11}]}}`
+	tests := []struct {
+		name  string
+		body  string
+		query Query
+		want  bool
+	}{
+		{"next page announced", withNext, Query{Limit: 10}, true},
+		{"no next page", `{"items":[{"title":"Bayam","link":"https://example.com/1"}]}`, Query{Limit: 10}, false},
+		{"no results", `{}`, Query{Limit: 10}, false},
+		{"last page Google allows", withNext, Query{Limit: 10, Start: 90}, false},
+	}
+	for _, tt := range tests {
+		g := NewGoogle("key", "cx")
+		g.client = &http.Client{Transport: &recordTransport{body: tt.body}}
+		tt.query.Text = "bayam"
+		p, err := g.Search(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if p.More != tt.want {
+			t.Errorf("%s: More = %v, want %v", tt.name, p.More, tt.want)
+		}
+	}
+}
+
+func TestGoogleErrorHidesKey(t *testing.T) {
+	// A cancelled request fails before reaching the network
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewGoogle("secret-key", "cx").Search(ctx, Query{Text: "zat besi"})
+	if err == nil {
+		t.Fatal("Search succeeded with a cancelled context")
+	}
+	if strings.Contains(err.Error(), "secret-key") || strings.Contains(err.Error(), "zat") {
+		t.Errorf("error %q contains the request query", err)
+	}
+}
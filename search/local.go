@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// LocalDocument is an article in the local index file, a JSON array of
+// these. Content is searched but not returned; Lang is optional.
+type LocalDocument struct {
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Snippet string `json:"snippet"`
+	Content string `json:"content"`
+	Lang    string `json:"lang"`
+}
+
+// titleWeight makes a word in the title count more than one in the body.
+const titleWeight = 3
+
+// Local is an in-memory full-text index, used offline or when Google is
+// unavailable.
+type Local struct {
+	docs []LocalDocument
+	// terms maps each word to the weighted count per document.
+	terms map[string]map[int]int
+}
+
+// LoadLocal reads and indexes the articles in a JSON file.
+func LoadLocal(path string) (*Local, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SEARCH_INDEX_FILE is not set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading search index: %v", err)
+	}
+
+	var docs []LocalDocument
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("error parsing search index: %v", err)
+	}
+	return NewLocal(docs), nil
+}
+
+// NewLocal indexes docs.
+func NewLocal(docs []LocalDocument) *Local {
+	l := &Local{docs: docs, terms: make(map[string]map[int]int)}
+	for i, doc := range docs {
+		for _, word := range tokenize(doc.Title) {
+			l.add(word, i, titleWeight)
+		}
+		for _, word := range tokenize(doc.Snippet + " " + doc.Content) {
+			l.add(word, i, 1)
+		}
+	}
+	return l
+}
+
+func (l *Local) add(word string, doc, weight int) {
+	if l.terms[word] == nil {
+		l.terms[word] = make(map[int]int)
+	}
+	l.terms[word][doc] += weight
+}
+
+func (l *Local) Name() string {
+	return "local"
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) > 1 {
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+// Search ranks documents by TF-IDF over the query words. Documents in
+// another language than the query are skipped.
+func (l *Local) Search(ctx context.Context, q Query) (Page, error) {
+	scores := make(map[int]float64)
+	for _, word := range tokenize(q.Text) {
+		postings := l.terms[word]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(len(l.docs))/float64(len(postings)))
+		for doc, count := range postings {
+			scores[doc] += (1 + math.Log(float64(count))) * idf
+		}
+	}
+
+	var ranked []int
+	for doc := range scores {
+		if lang := l.docs[doc].Lang; q.Lang != "" && lang != "" && lang != q.Lang {
+			continue
+		}
+		ranked = append(ranked, doc)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if scores[ranked[i]] != scores[ranked[j]] {
+			return scores[ranked[i]] > scores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	ranked, more := page(ranked, q)
+
+	articles := []Article{}
+	for _, doc := range ranked {
+		d := l.docs[doc]
+		articles = append(articles, Article{Title: d.Title, Link: d.Link, Snippet: d.Snippet})
+	}
+	return Page{Articles: articles, More: more}, nil
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLocalSearch(t *testing.T) {
+	local := NewLocal([]LocalDocument{
+		{Title: "Zat besi saat menstruasi", Link: "a", Snippet: "Bayam dan hati ayam", Lang: "id"},
+		{Title: "Iron during your period", Link: "b", Snippet: "Spinach and lentils for iron", Lang: "en"},
+		{Title: "Protein murah", Link: "c", Content: "tempe tahu telur, juga bayam", Lang: "id"},
+		{Title: "Bayam bayam bayam", Link: "d", Content: "bayam"},
+	})
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"title matches rank first", Query{Text: "bayam"}, []string{"d", "a", "c"}},
+		{"other languages are skipped", Query{Text: "iron bayam", Lang: "id"}, []string{"d", "a", "c"}},
+		{"documents without a language match any", Query{Text: "iron", Lang: "en"}, []string{"b"}},
+		{"paged", Query{Text: "bayam", Start: 1, Limit: 1}, []string{"a"}},
+		{"past the end", Query{Text: "bayam", Start: 5}, []string{}},
+		{"no match", Query{Text: "durian"}, []string{}},
+		{"one letter words are ignored", Query{Text: "a"}, []string{}},
+	}
+	for _, tt := range tests {
+		p, err := local.Search(context.Background(), tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		links := []string{}
+		for _, a := range p.Articles {
+			links = append(links, a.Link)
+		}
+		if !reflect.DeepEqual(links, tt.want) {
+			t.Errorf("%s: links = %v, want %v", tt.name, links, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Article is one search result. Credibility is set by Trust, see
+// CredibilityTrusted.
+type Article struct {
+	Title       string `json:"title"`
+	Link        string `json:"link"`
+	Snippet     string `json:"snippet"`
+	Credibility string `json:"credibility,omitempty"`
+}
+
+// Query is a search request. Lang is a language code such as "id". Start
+// is the zero-based offset of the first result, for paging.
+type Query struct {
+	Text  string
+	Lang  string
+	Limit int
+	Start int
+}
+
+// Page is one page of results. More is set when the provider has results
+// after this page; it is kept when Trust drops articles from the page, so
+// a short page is not mistaken for the last one.
+type Page struct {
+	Articles []Article
+	More     bool
+}
+
+// Searcher finds articles for a query.
+type Searcher interface {
+	Name() string
+	Search(ctx context.Context, q Query) (Page, error)
+}
+
+var searcher Searcher
+
+// Setup creates the searcher selected by SEARCH_PROVIDER (google, local or
+// fake, defaulting to google). The local index is read from
+// SEARCH_INDEX_FILE; when that is set with google, the local index answers
+// whenever Google fails.
+// Google results are cached for SEARCH_CACHE_TTL (a duration such as
+// "30m", default one hour, "0" disables the cache). All results are vetted
+// against SEARCH_TRUSTED_DOMAINS and SEARCH_DENIED_DOMAINS, see Trust.
+func Setup() error {
+	ttl := time.Hour
+	if v := os.Getenv("SEARCH_CACHE_TTL"); v != "" {
+		var err error
+		if ttl, err = time.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid SEARCH_CACHE_TTL %q: %v", v, err)
+		}
+	}
+	indexFile := os.Getenv("SEARCH_INDEX_FILE")
+
+	switch name := os.Getenv("SEARCH_PROVIDER"); name {
+	case "", "google":
+		searcher = NewGoogle(os.Getenv("Custom_Search_API_KEY"), os.Getenv("cx"))
+		// Only Google answers are cached, so fallback results are not kept
+		// around once Google recovers
+		if ttl > 0 {
+			searcher = NewCached(searcher, ttl)
+		}
+		if indexFile != "" {
+			local, err := LoadLocal(indexFile)
+			if err != nil {
+				return err
+			}
+			searcher = &Fallback{Primary: searcher, Secondary: local}
+		}
+	case "local":
+		local, err := LoadLocal(indexFile)
+		if err != nil {
+			return err
+		}
+		searcher = local
+	case "fake":
+		searcher = NewFake()
+	default:
+		return fmt.Errorf("unknown SEARCH_PROVIDER %q", name)
+	}
+
+	trust := NewTrust(searcher, DefaultTrustedDomains, nil)
+	if v, ok := os.LookupEnv("SEARCH_TRUSTED_DOMAINS"); ok {
+		trust.Trusted = splitDomains(v)
+	}
+	trust.Denied = splitDomains(os.Getenv("SEARCH_DENIED_DOMAINS"))
+	trust.TrustedOnly = os.Getenv("SEARCH_TRUSTED_ONLY") == "true"
+	searcher = trust
+
+	log.Printf("Using %s article search, cache TTL %s", searcher.Name(), ttl)
+	return nil
+}
+
+// Get returns the searcher created by Setup.
+func Get() Searcher {
+	return searcher
+}
+
+// SetSearcher replaces the shared searcher, e.g. with a fake.
+func SetSearcher(s Searcher) {
+	searcher = s
+}
+
+// Fallback searches Primary and, when it fails, Secondary.
+type Fallback struct {
+	Primary   Searcher
+	Secondary Searcher
+}
+
+func (f *Fallback) Name() string {
+	return f.Primary.Name() + " with " + f.Secondary.Name() + " fallback"
+}
+
+func (f *Fallback) Search(ctx context.Context, q Query) (Page, error) {
+	p, err := f.Primary.Search(ctx, q)
+	if err == nil {
+		return p, nil
+	}
+	if ctx.Err() != nil {
+		return Page{}, err
+	}
+
+	log.Printf("%s search failed, using %s: %v", f.Primary.Name(), f.Secondary.Name(), err)
+	return f.Secondary.Search(ctx, q)
+}
+
+// page cuts the results of q out of a ranked list and reports whether
+// more follow.
+func page[T any](items []T, q Query) ([]T, bool) {
+	if q.Start > 0 {
+		if q.Start >= len(items) {
+			return items[:0], false
+		}
+		items = items[q.Start:]
+	}
+	if q.Limit > 0 && len(items) > q.Limit {
+		return items[:q.Limit], true
+	}
+	return items, false
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name     string
+		query    Query
+		want     []int
+		wantMore bool
+	}{
+		{"everything", Query{}, []int{1, 2, 3, 4, 5}, false},
+		{"limited", Query{Limit: 2}, []int{1, 2}, true},
+		{"offset", Query{Start: 3}, []int{4, 5}, false},
+		{"offset and limit", Query{Start: 1, Limit: 2}, []int{2, 3}, true},
+		{"last full page", Query{Start: 3, Limit: 2}, []int{4, 5}, false},
+		{"limit past the end", Query{Start: 4, Limit: 3}, []int{5}, false},
+		{"offset past the end", Query{Start: 5}, []int{}, false},
+	}
+	for _, tt := range tests {
+		got, more := page(items, tt.query)
+		if !reflect.DeepEqual(got, tt.want) || more != tt.wantMore {
+			t.Errorf("%s: page = %v, %v, want %v, %v", tt.name, got, more, tt.want, tt.wantMore)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	secondary := &Fake{Articles: []Article{{Title: "bayam", Link: "local"}}}
+	tests := []struct {
+		name    string
+		primary *Fake
+		want    []Article
+		wantErr bool
+	}{
+		{"primary answers", &Fake{Articles: []Article{{Title: "bayam", Link: "google"}}}, []Article{{Title: "bayam", Link: "google"}}, false},
+		{"primary fails", &Fake{Err: errors.New("quota exceeded")}, []Article{{Title: "bayam", Link: "local"}}, false},
+	}
+	for _, tt := range tests {
+		f := &Fallback{Primary: tt.primary, Secondary: secondary}
+		got, err := f.Search(context.Background(), Query{Text: "bayam"})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: err = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got.Articles, tt.want) {
+			t.Errorf("%s: articles = %v, want %v", tt.name, got.Articles, tt.want)
+		}
+	}
+
+	// A cancelled request is not retried
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &Fallback{Primary: &Fake{Err: context.Canceled}, Secondary: secondary}
+	if _, err := f.Search(ctx, Query{Text: "bayam"}); err == nil {
+		t.Error("cancelled search fell back")
+	}
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// Credibility of a result's source.
+const (
+	// CredibilityTrusted is a health authority or vetted health publisher,
+	// or an article written by Nutrishe's own nutritionists.
+	CredibilityTrusted = "trusted"
+	CredibilityUnknown = "unknown"
+)
+
+// DefaultTrustedDomains are used when SEARCH_TRUSTED_DOMAINS is not set.
+var DefaultTrustedDomains = []string{
+	"kemkes.go.id",
+	"who.int",
+	"unicef.org",
+	"nih.gov",
+	"cdc.gov",
+	"nhs.uk",
+	"mayoclinic.org",
+	"idai.or.id",
+	"alodokter.com",
+	"halodoc.com",
+	"hellosehat.com",
+}
+
+// Trust vets results by the domain of their link. Results from Denied
+// domains are dropped, the rest are annotated trusted or unknown depending
+// on whether they come from a Trusted domain. With TrustedOnly set, unknown
+// results are dropped too. A domain also covers its subdomains.
+//
+// Dropped results are not replaced, so a page may hold fewer than the
+// limit; Page.More still tells whether the provider has more.
+type Trust struct {
+	Searcher
+	Trusted     []string
+	Denied      []string
+	TrustedOnly bool
+}
+
+func NewTrust(s Searcher, trusted, denied []string) *Trust {
+	return &Trust{Searcher: s, Trusted: trusted, Denied: denied}
+}
+
+// splitDomains parses a comma separated list of domains.
+func splitDomains(list string) []string {
+	domains := []string{}
+	for _, d := range strings.Split(list, ",") {
+		if d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "www."); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Credibility returns the credibility of a link, or "" when its domain is
+// denied.
+func (t *Trust) Credibility(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return CredibilityUnknown
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case matchDomain(host, t.Denied):
+		return ""
+	case matchDomain(host, t.Trusted):
+		return CredibilityTrusted
+	}
+	return CredibilityUnknown
+}
+
+func (t *Trust) Search(ctx context.Context, q Query) (Page, error) {
+	p, err := t.Searcher.Search(ctx, q)
+	if err != nil {
+		return Page{}, err
+	}
+
+	vetted := []Article{}
+	for _, a := range p.Articles {
+		a.Credibility = t.Credibility(a.Link)
+		if a.Credibility == "" || (t.TrustedOnly && a.Credibility != CredibilityTrusted) {
+			continue
+		}
+		vetted = append(vetted, a)
+	}
+	return Page{Articles: vetted, More: p.More}, nil
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSplitDomains(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", []string{}},
+		{"who.int", []string{"who.int"}},
+		{" WWW.Kemkes.go.id , ,nhs.uk ", []string{"kemkes.go.id", "nhs.uk"}},
+	}
+	for _, tt := range tests {
+		if got := splitDomains(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitDomains(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	domains := []string{"who.int", "kemkes.go.id"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"who.int", true},
+		{"www.who.int", true},
+		{"ayosehat.kemkes.go.id", true},
+		{"notwho.int", false},
+		{"who.int.example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := matchDomain(tt.host, domains); got != tt.want {
+			t.Errorf("matchDomain(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCredibility(t *testing.T) {
+	trust := NewTrust(nil, []string{"who.int"}, []string{"hoax.example.com"})
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://www.who.int/news", CredibilityTrusted},
+		{"https://WHO.INT/news", CredibilityTrusted},
+		{"https://blog.example.com/diet", CredibilityUnknown},
+		{"https://hoax.example.com/detox", ""},
+		{"https://cdn.hoax.example.com/detox", ""},
+		{"://not a link", CredibilityUnknown},
+	}
+	for _, tt := range tests {
+		if got := trust.Credibility(tt.link); got != tt.want {
+			t.Errorf("Credibility(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestTrustSearch(t *testing.T) {
+	fake := &Fake{Articles: []Article{
+		{Title: "bayam", Link: "https://www.who.int/iron"},
+		{Title: "bayam", Link: "https://blog.example.com/iron"},
+		{Title: "bayam", Link: "https://hoax.example.com/iron"},
+		{Title: "bayam", Link: "https://www.who.int/next-page"},
+	}}
+	tests := []struct {
+		name        string
+		trustedOnly bool
+		want        []Article
+	}{
+		{"denied are dropped", false, []Article{
+			{Title: "bayam", Link: "https://www.who.int/iron", Credibility: CredibilityTrusted},
+			{Title: "bayam", Link: "https://blog.example.com/iron", Credibility: CredibilityUnknown},
+		}},
+		{"trusted only", true, []Article{
+			{Title: "bayam", Link: "https://www.who.int/iron", Credibility: CredibilityTrusted},
+		}},
+	}
+	for _, tt := range tests {
+		trust := NewTrust(fake, []string{"who.int"}, []string{"hoax.example.com"})
+		trust.TrustedOnly = tt.trustedOnly
+		got, err := trust.Search(context.Background(), Query{Text: "bayam", Limit: 3})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got.Articles, tt.want) {
+			t.Errorf("%s: articles = %v, want %v", tt.name, got.Articles, tt.want)
+		}
+		// The short page is not the last one
+		if !got.More {
+			t.Errorf("%s: More = false, want true", tt.name)
+		}
+	}
+}